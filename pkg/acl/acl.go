@@ -0,0 +1,155 @@
+package acl
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type Permission string
+
+const (
+	List   Permission = "list"
+	Read   Permission = "read"
+	Upload Permission = "upload"
+	Delete Permission = "delete"
+)
+
+const (
+	Everyone      = "*"
+	Anonymous     = "anonymous"
+	Authenticated = "authenticated"
+	GroupPrefix   = "group:"
+	UserPrefix    = "user:"
+)
+
+type Rules struct {
+	Groups map[string][]string `json:"groups" yaml:"groups"`
+	Rules  []Rule              `json:"rules"  yaml:"rules"`
+}
+
+type Rule struct {
+	Path        string       `json:"path"        yaml:"path"`
+	Principals  []string     `json:"principals"  yaml:"principals"`
+	Permissions []Permission `json:"permissions" yaml:"permissions"`
+}
+
+func Load(name string) (*Rules, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+
+	err = yaml.Unmarshal(content, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	for i, rule := range rules.Rules {
+		if rule.Path == "" {
+			return nil, fmt.Errorf("%s: rule %d: missing path", name, i)
+		}
+
+		for _, permission := range rule.Permissions {
+			switch permission {
+			case List, Read, Upload, Delete:
+
+			default:
+				return nil, fmt.Errorf("%s: rule %d: invalid permission %q", name, i, permission)
+			}
+		}
+	}
+
+	return &rules, nil
+}
+
+func (r *Rules) Allowed(principal, filePath string, permission Permission) bool {
+	if r == nil {
+		return true
+	}
+
+	filePath = path.Clean("/" + filePath)
+
+	for _, rule := range r.Rules {
+		if !matchPath(rule.Path, filePath) {
+			continue
+		}
+
+		return slices.Contains(rule.Permissions, permission) &&
+			slices.ContainsFunc(rule.Principals, func(p string) bool { return r.matchPrincipal(p, principal) })
+	}
+
+	return true
+}
+
+func (r *Rules) matchPrincipal(pattern, principal string) bool {
+	switch {
+	case pattern == Everyone:
+		return true
+
+	case pattern == Anonymous:
+		return principal == ""
+
+	case pattern == Authenticated:
+		return principal != ""
+
+	case strings.HasPrefix(pattern, GroupPrefix):
+		return principal != "" && slices.Contains(r.Groups[strings.TrimPrefix(pattern, GroupPrefix)], principal)
+
+	default:
+		return principal != "" && strings.TrimPrefix(pattern, UserPrefix) == principal
+	}
+}
+
+func matchPath(pattern, filePath string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	patternParts := strings.Split(strings.Trim(path.Clean("/"+pattern), "/"), "/")
+	pathParts := strings.Split(strings.Trim(filePath, "/"), "/")
+
+	if patternParts[0] == "" {
+		patternParts = nil
+	}
+
+	if pathParts[0] == "" {
+		pathParts = nil
+	}
+
+	return matchParts(patternParts, pathParts)
+}
+
+func matchParts(patternParts, pathParts []string) bool {
+	for len(patternParts) > 0 {
+		if patternParts[0] == "**" {
+			for i := 0; i <= len(pathParts); i++ {
+				if matchParts(patternParts[1:], pathParts[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(pathParts) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(patternParts[0], pathParts[0])
+		if err != nil || !ok {
+			return false
+		}
+
+		patternParts = patternParts[1:]
+		pathParts = pathParts[1:]
+	}
+
+	return len(pathParts) == 0
+}
@@ -0,0 +1,145 @@
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		filePath string
+		ok       bool
+	}{
+		{pattern: "/", filePath: "/", ok: true},
+		{pattern: "/", filePath: "/a/b.txt", ok: true},
+		{pattern: "/docs", filePath: "/docs", ok: true},
+		{pattern: "/docs", filePath: "/docs/a.txt", ok: false},
+		{pattern: "/docs/", filePath: "/docs", ok: true},
+		{pattern: "/docs/", filePath: "/docs/a/b.txt", ok: true},
+		{pattern: "/docs/", filePath: "/docs-private/a.txt", ok: false},
+		{pattern: "docs/", filePath: "/docs/a.txt", ok: true},
+		{pattern: "/*.txt", filePath: "/a.txt", ok: true},
+		{pattern: "/*.txt", filePath: "/docs/a.txt", ok: false},
+		{pattern: "/*/a.txt", filePath: "/docs/a.txt", ok: true},
+		{pattern: "/**/*.txt", filePath: "/a.txt", ok: true},
+		{pattern: "/**/*.txt", filePath: "/docs/sub/a.txt", ok: true},
+		{pattern: "/**/*.txt", filePath: "/docs/a.md", ok: false},
+		{pattern: "/docs/**/secret", filePath: "/docs/secret", ok: true},
+		{pattern: "/docs/**/secret", filePath: "/docs/a/b/secret", ok: true},
+		{pattern: "/docs/**/secret", filePath: "/docs/a/b/secret/c", ok: false},
+		{pattern: "/docs/a?.txt", filePath: "/docs/ab.txt", ok: true},
+		{pattern: "/docs/[ab].txt", filePath: "/docs/c.txt", ok: false},
+		{pattern: "/docs/[", filePath: "/docs/[", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.filePath, func(t *testing.T) {
+			ok := matchPath(tt.pattern, tt.filePath)
+			if ok != tt.ok {
+				t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.filePath, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRulesAllowed(t *testing.T) {
+	rules := &Rules{
+		Groups: map[string][]string{
+			"staff": {"alice", "bob"},
+		},
+		Rules: []Rule{
+			{Path: "/public/", Principals: []string{Everyone}, Permissions: []Permission{List, Read}},
+			{Path: "/inbox/", Principals: []string{Anonymous}, Permissions: []Permission{Upload}},
+			{Path: "/members/", Principals: []string{Authenticated}, Permissions: []Permission{List, Read}},
+			{Path: "/staff/", Principals: []string{"group:staff"}, Permissions: []Permission{List, Read, Upload, Delete}},
+			{Path: "/alice/", Principals: []string{"user:alice"}, Permissions: []Permission{List, Read}},
+			{Path: "/carol/", Principals: []string{"carol"}, Permissions: []Permission{Read}},
+			{Path: "/**/*.secret", Principals: []string{"user:alice"}, Permissions: []Permission{Read}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		principal  string
+		filePath   string
+		permission Permission
+		ok         bool
+	}{
+		{name: "everyone anonymous", principal: "", filePath: "/public/a.txt", permission: Read, ok: true},
+		{name: "everyone user", principal: "bob", filePath: "/public/a.txt", permission: List, ok: true},
+		{name: "everyone missing permission", principal: "bob", filePath: "/public/a.txt", permission: Upload, ok: false},
+		{name: "anonymous", principal: "", filePath: "/inbox/a.txt", permission: Upload, ok: true},
+		{name: "anonymous user", principal: "bob", filePath: "/inbox/a.txt", permission: Upload, ok: false},
+		{name: "authenticated", principal: "bob", filePath: "/members/a.txt", permission: Read, ok: true},
+		{name: "authenticated anonymous", principal: "", filePath: "/members/a.txt", permission: Read, ok: false},
+		{name: "group member", principal: "bob", filePath: "/staff/a.txt", permission: Delete, ok: true},
+		{name: "group non member", principal: "carol", filePath: "/staff/a.txt", permission: Read, ok: false},
+		{name: "user prefix", principal: "alice", filePath: "/alice/a.txt", permission: Read, ok: true},
+		{name: "user prefix other user", principal: "bob", filePath: "/alice/a.txt", permission: Read, ok: false},
+		{name: "bare user", principal: "carol", filePath: "/carol/a.txt", permission: Read, ok: true},
+		{name: "first match wins", principal: "bob", filePath: "/public/a.secret", permission: Read, ok: true},
+		{name: "glob", principal: "alice", filePath: "/other/a.secret", permission: Read, ok: true},
+		{name: "glob other user", principal: "bob", filePath: "/other/a.secret", permission: Read, ok: false},
+		{name: "unclean path", principal: "bob", filePath: "public/../alice/a.txt", permission: Read, ok: false},
+		{name: "no matching rule", principal: "", filePath: "/other/a.txt", permission: Delete, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := rules.Allowed(tt.principal, tt.filePath, tt.permission)
+			if ok != tt.ok {
+				t.Errorf("Allowed(%q, %q, %q) = %v, want %v", tt.principal, tt.filePath, tt.permission, ok, tt.ok)
+			}
+		})
+	}
+
+	t.Run("nil rules", func(t *testing.T) {
+		var rules *Rules
+
+		if !rules.Allowed("", "/a.txt", Delete) {
+			t.Error("nil rules denied access")
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rules   int
+		ok      bool
+	}{
+		{
+			name:    "valid",
+			content: "groups:\n  staff: [alice]\nrules:\n  - path: /staff/\n    principals: [group:staff]\n    permissions: [list, read]\n",
+			rules:   1,
+			ok:      true,
+		},
+		{name: "empty", content: "", ok: true},
+		{name: "missing path", content: "rules:\n  - principals: ['*']\n    permissions: [read]\n", ok: false},
+		{name: "invalid permission", content: "rules:\n  - path: /\n    principals: ['*']\n    permissions: [write]\n", ok: false},
+		{name: "invalid yaml", content: "rules: [", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "acl.yaml")
+
+			err := os.WriteFile(name, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rules, err := Load(name)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Load() error = %v, want ok %v", err, tt.ok)
+			}
+
+			if err == nil && len(rules.Rules) != tt.rules {
+				t.Errorf("Load() loaded %d rules, want %d", len(rules.Rules), tt.rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package goserve
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+)
+
+func loadCredentials(users []string, htpasswd string) (*auth.Credentials, error) {
+	credentials := auth.NewCredentials()
+
+	for _, user := range users {
+		err := credentials.AddUser(user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if htpasswd != "" {
+		err := credentials.LoadHtpasswd(htpasswd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return credentials, nil
+}
+
+func formatAuth(users []string, htpasswd string) string {
+	var sources []string
+
+	for _, user := range users {
+		username, _, _ := strings.Cut(user, ":")
+
+		sources = append(sources, username)
+	}
+
+	if htpasswd != "" {
+		sources = append(sources, htpasswd)
+	}
+
+	return strings.Join(sources, ",")
+}
+
+func loadCertPool(name string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificates found in %s", name)
+	}
+
+	return pool, nil
+}
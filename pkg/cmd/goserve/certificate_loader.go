@@ -0,0 +1,142 @@
+package goserve
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const certificateReloadDelay = 250 * time.Millisecond
+
+type certificateLoader struct {
+	certFile    string
+	keyFile     string
+	certificate atomic.Pointer[tls.Certificate]
+}
+
+func newCertificateLoader(certFile, keyFile string) (*certificateLoader, error) {
+	loader := &certificateLoader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	err := loader.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return loader, nil
+}
+
+func (l *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return l.certificate.Load(), nil
+}
+
+func (l *certificateLoader) Certificate() tls.Certificate {
+	return *l.certificate.Load()
+}
+
+func (l *certificateLoader) load() error {
+	certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return err
+	}
+
+	l.certificate.Store(&certificate)
+
+	return nil
+}
+
+func (l *certificateLoader) reload(reason string) {
+	err := l.load()
+	if err != nil {
+		slog.Error("failed to reload tls certificate", "reason", reason, "cert", l.certFile, "key", l.keyFile, "error", err)
+
+		return
+	}
+
+	slog.Info("reloaded tls certificate", "reason", reason, "cert", l.certFile, "expires", l.certificate.Load().Leaf.NotAfter)
+}
+
+func (l *certificateLoader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := watcher.Close()
+		if err != nil {
+			slog.Error("failed to close tls certificate watcher", "error", err)
+		}
+	}()
+
+	certFile, err := filepath.Abs(l.certFile)
+	if err != nil {
+		return err
+	}
+
+	keyFile, err := filepath.Abs(l.keyFile)
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{
+		filepath.Dir(certFile): true,
+		filepath.Dir(keyFile):  true,
+	}
+
+	for dir := range dirs {
+		err = watcher.Add(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	defer signal.Stop(hangup)
+
+	timer := time.NewTimer(certificateReloadDelay)
+
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-hangup:
+			l.reload("signal")
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Name == certFile || event.Name == keyFile || filepath.Base(event.Name) == "..data" {
+				timer.Reset(certificateReloadDelay)
+			}
+
+		case <-timer.C:
+			l.reload("file change")
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			slog.Error("tls certificate watcher failed", "error", err)
+		}
+	}
+}
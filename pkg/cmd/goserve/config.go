@@ -3,7 +3,10 @@ package goserve
 import (
 	"bytes"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"text/tabwriter"
 )
 
@@ -38,6 +41,16 @@ func printConfigs(configs []config) error {
 	return tab.Flush()
 }
 
+func formatMap(m map[string]string) string {
+	var pairs []string
+
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		pairs = append(pairs, key+"="+m[key])
+	}
+
+	return strings.Join(pairs, ",")
+}
+
 func printlnf(format string, args ...any) {
 	fmt.Fprint(os.Stdout, sprintlnf(format, args...))
 }
@@ -0,0 +1,135 @@
+package goserve
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	configName = "goserve"
+	mountsKey  = "mounts"
+)
+
+var configAliases = map[string]string{
+	"auth-users":            "auth",
+	"auth-htpasswd":         "htpasswd",
+	"logging-format":        "log-format",
+	"logging-level":         "log-level",
+	"uploads-auth-users":    "uploads-auth",
+	"uploads-auth-htpasswd": "uploads-htpasswd",
+}
+
+func loadConfigFile(cmd *cobra.Command, args []string) error {
+	configFile := viper.GetString("config")
+
+	fileConfig := viper.NewWithOptions(viper.KeyDelimiter("::"))
+
+	if configFile != "" {
+		fileConfig.SetConfigFile(configFile)
+	} else {
+		fileConfig.SetConfigName(configName)
+		fileConfig.AddConfigPath(".")
+
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+
+		if configDir == "" {
+			homeDir, err := os.UserHomeDir()
+			if err == nil {
+				configDir = filepath.Join(homeDir, ".config")
+			}
+		}
+
+		if configDir != "" {
+			fileConfig.AddConfigPath(filepath.Join(configDir, configName))
+		}
+	}
+
+	err := fileConfig.ReadInConfig()
+	if err != nil {
+		var notFoundErr viper.ConfigFileNotFoundError
+
+		if configFile == "" && errors.As(err, &notFoundErr) {
+			return nil
+		}
+
+		return err
+	}
+
+	settings := make(map[string]any)
+
+	err = flattenConfig(cmd.Root(), "", fileConfig.AllSettings(), settings)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fileConfig.ConfigFileUsed(), err)
+	}
+
+	err = viper.MergeConfigMap(settings)
+	if err != nil {
+		return err
+	}
+
+	viper.Set("config", fileConfig.ConfigFileUsed())
+
+	return nil
+}
+
+func flattenConfig(root *cobra.Command, prefix string, config, settings map[string]any) error {
+	for key, value := range config {
+		name := key
+
+		if prefix != "" {
+			name = prefix + "-" + key
+
+			if key == "enabled" {
+				name = prefix
+			}
+		}
+
+		if name == mountsKey {
+			settings[name] = value
+
+			continue
+		}
+
+		alias, ok := configAliases[name]
+		if ok {
+			name = alias
+		}
+
+		section, isSection := value.(map[string]any)
+
+		flag := lookupFlag(root, name)
+
+		if flag != nil && (!isSection || flag.Value.Type() == "stringToString") {
+			settings[name] = value
+
+			continue
+		}
+
+		if !isSection {
+			return fmt.Errorf("unknown config key %q", name)
+		}
+
+		err := flattenConfig(root, name, section, settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func lookupFlag(root *cobra.Command, name string) *pflag.Flag {
+	flag := root.Flags().Lookup(name)
+
+	if flag == nil {
+		flag = root.PersistentFlags().Lookup(name)
+	}
+
+	return flag
+}
@@ -3,24 +3,33 @@ package goserve
 import (
 	"crypto/tls"
 	"errors"
-	"io/fs"
 	"log/slog"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
+	"os/signal"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/pkg/browser"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/cmgsj/goserve/pkg/acl"
 	"github.com/cmgsj/goserve/pkg/files"
+	"github.com/cmgsj/goserve/pkg/middleware/altsvc"
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+	"github.com/cmgsj/goserve/pkg/middleware/compress"
 	"github.com/cmgsj/goserve/pkg/middleware/logging"
+	"github.com/cmgsj/goserve/pkg/middleware/proxy"
+	"github.com/cmgsj/goserve/pkg/middleware/redirect"
+	"github.com/cmgsj/goserve/pkg/share"
 )
 
 var banner = heredoc.Doc(`
@@ -35,52 +44,141 @@ var version = "dev"
 
 func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "goserve {file|dir}",
+		Use:   "goserve {file|dir}[:prefix]...",
 		Short: "HTTP file server",
 		Long:  banner + "\n" + "HTTP file server",
 		CompletionOptions: cobra.CompletionOptions{
 			HiddenDefaultCmd: true,
 		},
-		SilenceErrors: true,
-		SilenceUsage:  true,
-		Args:          cobra.ExactArgs(1),
-		RunE:          run,
-		Version:       version,
+		SilenceErrors:     true,
+		SilenceUsage:      true,
+		Args:              cobra.ArbitraryArgs,
+		PersistentPreRunE: loadConfigFile,
+		RunE:              run,
+		Version:           version,
 	}
 
+	cmd.Flags().String("acl", "", "access control rules file")
+	cmd.Flags().StringArray("auth", nil, "basic auth credentials {user:pass}")
+	cmd.Flags().String("base-path", "", "url base path")
+	cmd.Flags().Bool("compress", false, "compress responses")
+	cmd.Flags().Int("compress-min-size", 1024, "minimum response size in bytes to compress")
+	cmd.Flags().Bool("delete", false, "enable deleting files with DELETE requests")
 	cmd.Flags().String("exclude", "", "exclude file pattern")
+	cmd.Flags().Bool("h2c", false, "serve http2 over cleartext on plain listeners")
 	cmd.Flags().String("host", "", "http host")
+	cmd.Flags().String("htpasswd", "", "basic auth htpasswd file")
+	cmd.Flags().Duration("idle-timeout", 2*time.Minute, "http idle timeout")
+	cmd.Flags().Bool("http3", false, "serve http3 over quic on tls listener ports")
+	cmd.Flags().Bool("index", false, "serve index files for directories")
+	cmd.Flags().StringSlice("index-names", files.DefaultIndexNames, "index file names")
+	cmd.Flags().StringArray("listen", nil, "listen address {[http|https://]host:port|unix:path}")
+	cmd.Flags().String("listen-mode", "", "unix socket file mode {0660}")
 	cmd.Flags().String("log-format", "text", "log format {json|text}")
 	cmd.Flags().String("log-level", "info", "log level {debug|info|warn|error}")
+	cmd.Flags().Int("max-header-bytes", http.DefaultMaxHeaderBytes, "http max header bytes")
+	cmd.Flags().StringToString("mime-types", nil, "mime type overrides by file extension {ext=type}")
 	cmd.Flags().Bool("open", false, "open browser")
 	cmd.Flags().Uint64("port", 0, "http port")
+	cmd.Flags().Bool("precompressed", false, "serve precompressed file variants {.zst|.br|.gz}")
+	cmd.Flags().Bool("precompressed-hide", false, "hide precompressed file variants from listings")
+	cmd.Flags().Bool("put", false, "enable writing files with PUT requests")
+	cmd.Flags().Duration("read-header-timeout", 10*time.Second, "http read header timeout")
+	cmd.Flags().Duration("read-timeout", 0, "http read timeout")
+	cmd.Flags().Bool("redirect-https", false, "redirect plain http requests to https")
+	cmd.Flags().Duration("shutdown-timeout", 30*time.Second, "graceful shutdown timeout")
+	cmd.Flags().Bool("spa", false, "serve the root index file for unknown paths")
+	cmd.Flags().Bool("tls-auto", false, "generate a self-signed tls certificate")
+	cmd.Flags().String("tls-auto-cache", "", "self-signed tls certificate cache directory")
 	cmd.Flags().String("tls-cert", "", "tls cert file")
+	cmd.Flags().String("tls-client-ca", "", "tls client ca file")
+	cmd.Flags().StringSlice("tls-client-read", nil, "tls client identities allowed to read {cn|san|*}")
+	cmd.Flags().StringSlice("tls-client-upload", nil, "tls client identities allowed to upload {cn|san|*}")
 	cmd.Flags().String("tls-key", "", "tls key file")
 	cmd.Flags().Bool("uploads", false, "enable uploads")
+	cmd.Flags().StringArray("uploads-auth", nil, "uploads basic auth credentials {user:pass}")
+	cmd.Flags().String("uploads-conflict", string(files.ConflictReject), "uploads existing file policy {reject|overwrite|rename|version}")
 	cmd.Flags().String("uploads-dir", "", "uploads directory")
+	cmd.Flags().String("uploads-htpasswd", "", "uploads basic auth htpasswd file")
+	cmd.Flags().Bool("uploads-in-place", false, "upload into the browsed directory instead of the uploads directory")
+	cmd.Flags().Int64("uploads-max-size", 0, "uploads max size in bytes")
+	cmd.Flags().String("uploads-name", "", "uploads name template {name|base|ext|date|time|datetime|uuid|ip}")
 	cmd.Flags().Bool("uploads-timestamp", false, "add upload timestamp")
+	cmd.Flags().Duration("uploads-tus-expiration", files.DefaultTusExpiration, "resumable uploads expiration")
+	cmd.Flags().StringSlice("trusted-proxies", nil, "trusted proxy addresses, cidrs or unix for x-forwarded headers")
+	cmd.Flags().Duration("write-timeout", 0, "http write timeout")
+
+	cmd.PersistentFlags().String("config", "", "config file {json|toml|yaml}")
+	cmd.PersistentFlags().String("sign-key", "", "share link signing key")
+
+	cmd.AddCommand(newSignCommand())
 
 	viper.AutomaticEnv()
 	viper.AllowEmptyEnv(true)
 	viper.SetEnvPrefix("goserve")
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.BindPFlags(cmd.Flags())
+	viper.BindPFlags(cmd.PersistentFlags())
 
 	return cmd
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	aclFile := viper.GetString("acl")
+	authUsers := viper.GetStringSlice("auth")
+	basePath := viper.GetString("base-path")
+	compressResponses := viper.GetBool("compress")
+	compressMinSize := viper.GetInt("compress-min-size")
+	deleteFiles := viper.GetBool("delete")
 	exclude := viper.GetString("exclude")
+	h2c := viper.GetBool("h2c")
 	host := viper.GetString("host")
+	htpasswd := viper.GetString("htpasswd")
+	idleTimeout := viper.GetDuration("idle-timeout")
+	http3Enabled := viper.GetBool("http3")
+	index := viper.GetBool("index")
+	indexNames := viper.GetStringSlice("index-names")
+	listenAddresses := viper.GetStringSlice("listen")
+	listenMode := viper.GetString("listen-mode")
 	logFormat := viper.GetString("log-format")
 	logLevel := viper.GetString("log-level")
+	maxHeaderBytes := viper.GetInt("max-header-bytes")
+	mimeTypes := viper.GetStringMapString("mime-types")
 	port := viper.GetUint64("port")
+	open := viper.GetBool("open")
+	precompressed := viper.GetBool("precompressed")
+	precompressedHide := viper.GetBool("precompressed-hide")
+	putFiles := viper.GetBool("put")
+	readHeaderTimeout := viper.GetDuration("read-header-timeout")
+	readTimeout := viper.GetDuration("read-timeout")
+	redirectHTTPS := viper.GetBool("redirect-https")
+	shutdownTimeout := viper.GetDuration("shutdown-timeout")
+	signKey := viper.GetString("sign-key")
+	spa := viper.GetBool("spa")
+	tlsAuto := viper.GetBool("tls-auto")
+	tlsAutoCache := viper.GetString("tls-auto-cache")
 	tlsCert := viper.GetString("tls-cert")
+	tlsClientCA := viper.GetString("tls-client-ca")
+	tlsClientRead := viper.GetStringSlice("tls-client-read")
+	tlsClientUpload := viper.GetStringSlice("tls-client-upload")
 	tlsKey := viper.GetString("tls-key")
-	open := viper.GetBool("open")
+	trustedProxies := viper.GetStringSlice("trusted-proxies")
 	uploads := viper.GetBool("uploads")
+	uploadsAuthUsers := viper.GetStringSlice("uploads-auth")
+	uploadsConflict := viper.GetString("uploads-conflict")
 	uploadsDir := viper.GetString("uploads-dir")
+	uploadsHtpasswd := viper.GetString("uploads-htpasswd")
+	uploadsInPlace := viper.GetBool("uploads-in-place")
+	uploadsMaxSize := viper.GetInt64("uploads-max-size")
+	uploadsName := viper.GetString("uploads-name")
 	uploadsTimestamp := viper.GetBool("uploads-timestamp")
+	uploadsTusExpiration := viper.GetDuration("uploads-tus-expiration")
+	writeTimeout := viper.GetDuration("write-timeout")
+
+	var uploadsEnabled, writesEnabled bool
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	err := initLogger(loggerOptions{
 		format: logFormat,
@@ -90,66 +188,84 @@ func run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	path := args[0]
+	mountConfigs := make([]mountConfig, 0, len(args))
 
-	path, err = filepath.Abs(path)
+	for _, arg := range args {
+		mountConfigs = append(mountConfigs, parseMount(arg))
+	}
+
+	var fileMountConfigs []mountConfig
+
+	err = viper.UnmarshalKey(mountsKey, &fileMountConfigs)
 	if err != nil {
 		return err
 	}
 
-	pathInfo, err := os.Stat(path)
+	mountConfigs, err = resolveMounts(append(mountConfigs, fileMountConfigs...))
 	if err != nil {
 		return err
 	}
 
-	var fileSystem fs.FS
+	mountDefaults := mountConfig{
+		Exclude:          &exclude,
+		Uploads:          &uploads,
+		UploadsDir:       &uploadsDir,
+		UploadsInPlace:   &uploadsInPlace,
+		UploadsMaxSize:   &uploadsMaxSize,
+		UploadsTimestamp: &uploadsTimestamp,
+		UploadsConflict:  &uploadsConflict,
+		UploadsName:      &uploadsName,
+		Put:              &putFiles,
+		Delete:           &deleteFiles,
+	}
 
-	if pathInfo.IsDir() {
-		fileSystem = os.DirFS(path)
-	} else {
-		fileSystem = os.DirFS(filepath.Dir(path))
+	mounts := make([]mount, 0, len(mountConfigs))
 
-		fileSystem, err = fs.Sub(fileSystem, filepath.Base(path))
+	for _, mountConfig := range mountConfigs {
+		mount, err := loadMount(mountConfig, mountDefaults)
 		if err != nil {
 			return err
 		}
+
+		mounts = append(mounts, mount)
+
+		uploadsEnabled = uploadsEnabled || mount.uploads
+		writesEnabled = writesEnabled || mount.uploads || mount.put || mount.delete
 	}
 
-	var excludePattern *regexp.Regexp
+	basePath = strings.TrimSuffix(path.Clean("/"+basePath), "/")
 
-	if exclude != "" {
-		excludePattern, err = regexp.Compile(exclude)
-		if err != nil {
-			return err
-		}
+	proxyOptions, err := proxy.ParseTrustedProxies(trustedProxies)
+	if err != nil {
+		return err
 	}
 
-	if uploads {
-		if uploadsDir == "" {
-			uploadsDir = os.TempDir()
-		}
+	var aclRules *acl.Rules
 
-		uploadsDirAbs, err := filepath.Abs(uploadsDir)
+	if aclFile != "" {
+		aclRules, err = acl.Load(aclFile)
 		if err != nil {
 			return err
 		}
+	}
+
+	credentials, err := loadCredentials(authUsers, htpasswd)
+	if err != nil {
+		return err
+	}
 
-		uploadsDir = uploadsDirAbs
+	uploadsCredentials, err := loadCredentials(uploadsAuthUsers, uploadsHtpasswd)
+	if err != nil {
+		return err
+	}
 
-		_, err = os.Stat(uploadsDir)
-		if err != nil {
-			if !errors.Is(err, fs.ErrNotExist) {
-				return err
-			}
+	var signer *share.Signer
 
-			err = os.MkdirAll(uploadsDir, 0o750)
-			if err != nil {
-				return err
-			}
-		}
+	if signKey != "" {
+		signer = share.NewSigner([]byte(signKey))
 	}
 
-	serveTLS := tlsCert != "" && tlsKey != ""
+	serveTLS := (tlsCert != "" && tlsKey != "") || tlsAuto
 
 	if host == "" {
 		host = "0.0.0.0"
@@ -163,41 +279,111 @@ func run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	address := net.JoinHostPort(host, strconv.FormatUint(port, 10))
+	if http3Enabled && !serveTLS {
+		return errors.New("http3 requires a tls certificate")
+	}
+
+	if tlsClientCA != "" && !serveTLS {
+		return errors.New("tls client ca requires a tls certificate")
+	}
 
-	listener, err := net.Listen("tcp", address)
+	listeners, err := openListeners(listenAddresses, listenMode, net.JoinHostPort(host, strconv.FormatUint(port, 10)), serveTLS)
 	if err != nil {
 		return err
 	}
 
-	scheme := "http"
+	customListeners := len(listenAddresses) > 0 || !listeners[0].configured
+
+	if redirectHTTPS && !slices.ContainsFunc(listeners, func(l serverListener) bool { return l.tls }) {
+		return errors.New("https redirect requires a tls listener")
+	}
+
+	if tlsClientCA != "" && slices.ContainsFunc(listeners, func(l serverListener) bool { return !l.tls }) {
+		closeListeners(listeners)
+
+		return errors.New("tls client ca requires every listener to use tls")
+	}
+
+	var (
+		certificate tls.Certificate
+		tlsConfig   *tls.Config
+	)
 
 	if serveTLS {
-		scheme = "https"
+		tlsConfig = &tls.Config{
+			NextProtos: []string{"h2", "http/1.1"},
+		}
 
-		certificate, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
-		if err != nil {
-			return err
+		if tlsAuto {
+			certificate, err = autoCertificate(tlsAutoCache, append([]string{host}, listenerHosts(listenAddresses, listeners)...))
+			if err != nil {
+				return err
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		} else {
+			loader, err := newCertificateLoader(tlsCert, tlsKey)
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				err := loader.watch(ctx)
+				if err != nil {
+					slog.Error("failed to watch tls certificate", "error", err)
+				}
+			}()
+
+			certificate = loader.Certificate()
+
+			tlsConfig.GetCertificate = loader.GetCertificate
 		}
 
-		listener = tls.NewListener(listener, &tls.Config{
-			Certificates: []tls.Certificate{certificate},
-		})
-	}
+		if tlsClientCA != "" {
+			clientCAs, err := loadCertPool(tlsClientCA)
+			if err != nil {
+				return err
+			}
 
-	url := &url.URL{
-		Scheme: scheme,
-		Host:   strings.ReplaceAll(address, "0.0.0.0", "localhost"),
+			tlsConfig.ClientCAs = clientCAs
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		for i := range listeners {
+			if listeners[i].tls {
+				listeners[i].Listener = tls.NewListener(listeners[i].Listener, tlsConfig)
+			}
+		}
 	}
 
-	controller := files.NewController(fileSystem, files.ControllerConfig{
-		FilesURL:         "/",
-		ExcludePattern:   excludePattern,
-		Uploads:          uploads,
-		UploadsDir:       uploadsDir,
-		UploadsTimestamp: uploadsTimestamp,
-		Version:          version,
-	})
+	controllers := make([]*files.Controller, 0, len(mounts))
+
+	for _, mount := range mounts {
+		controllers = append(controllers, files.NewController(mount.fileSystem, files.ControllerConfig{
+			FilesURL:             mount.url(basePath),
+			MountPath:            mount.prefix,
+			Root:                 mount.path,
+			ExcludePattern:       mount.excludePattern,
+			ACL:                  aclRules,
+			ContentTypes:         mimeTypes,
+			Precompressed:        precompressed,
+			PrecompressedHide:    precompressedHide,
+			Signer:               signer,
+			Index:                index,
+			IndexNames:           indexNames,
+			SPA:                  spa,
+			Uploads:              mount.uploads,
+			UploadsDir:           mount.uploadsDir,
+			UploadsInPlace:       mount.uploadsInPlace,
+			UploadsMaxSize:       mount.uploadsMaxSize,
+			UploadsConflict:      mount.uploadsConflict,
+			UploadsName:          mount.uploadsName,
+			UploadsTusExpiration: uploadsTusExpiration,
+			Put:                  mount.put,
+			Delete:               mount.delete,
+			Version:              version,
+		}))
+	}
 
 	printlnf("")
 
@@ -209,28 +395,126 @@ func run(cmd *cobra.Command, args []string) error {
 	printlnf("")
 	printlnf("Config:")
 
-	err = printConfigs([]config{
+	configFile := viper.GetString("config")
+
+	err = printConfigs(slices.Concat([]config{
+		{
+			key:      "Config File",
+			value:    configFile,
+			disabled: configFile == "",
+		},
+		{
+			key:      "Base Path",
+			value:    basePath,
+			disabled: basePath == "",
+		},
+		{
+			key:      "Trusted Proxies",
+			value:    strings.Join(trustedProxies, ","),
+			disabled: len(trustedProxies) == 0,
+		},
+		{
+			key:      "Host",
+			value:    host,
+			disabled: customListeners,
+		},
+		{
+			key:      "Port",
+			value:    port,
+			disabled: customListeners,
+		},
+	}, listenerConfigRows(listeners, customListeners), []config{
+		{
+			key:      "Listen Mode",
+			value:    listenMode,
+			disabled: listenMode == "",
+		},
+		{
+			key:      "H2C",
+			value:    h2c,
+			disabled: !h2c,
+		},
+		{
+			key:      "HTTP/3",
+			value:    http3Enabled,
+			disabled: !http3Enabled,
+		},
+		{
+			key:      "Redirect HTTPS",
+			value:    redirectHTTPS,
+			disabled: !redirectHTTPS,
+		},
+		{
+			key:      "ACL",
+			value:    aclFile,
+			disabled: aclRules == nil,
+		},
 		{
-			key:   "Path",
-			value: path,
+			key:      "MIME Types",
+			value:    formatMap(mimeTypes),
+			disabled: len(mimeTypes) == 0,
 		},
 		{
-			key:   "Host",
-			value: host,
+			key:      "Index Names",
+			value:    strings.Join(indexNames, ","),
+			disabled: !index && !spa,
 		},
 		{
-			key:   "Port",
-			value: port,
+			key:      "SPA",
+			value:    spa,
+			disabled: !spa,
 		},
 		{
-			key:      "Exclude Pattern",
-			value:    excludePattern,
-			disabled: excludePattern == nil,
+			key:      "Compress Min Size",
+			value:    files.FormatSizeMetric(float64(compressMinSize), files.ShortestLengthPrecision),
+			disabled: !compressResponses,
 		},
 		{
-			key:      "Uploads Dir",
-			value:    uploadsDir,
-			disabled: !uploads,
+			key:      "Auth",
+			value:    formatAuth(authUsers, htpasswd),
+			disabled: credentials.Empty(),
+		},
+		{
+			key:      "Uploads Auth",
+			value:    formatAuth(uploadsAuthUsers, uploadsHtpasswd),
+			disabled: uploadsCredentials.Empty() || !writesEnabled,
+		},
+		{
+			key:      "Uploads Tus Expiration",
+			value:    uploadsTusExpiration,
+			disabled: !uploadsEnabled,
+		},
+		{
+			key:      "Share Links",
+			value:    "enabled",
+			disabled: signer == nil,
+		},
+		{
+			key:   "Read Header Timeout",
+			value: readHeaderTimeout,
+		},
+		{
+			key:      "Read Timeout",
+			value:    readTimeout,
+			disabled: readTimeout <= 0,
+		},
+		{
+			key:      "Write Timeout",
+			value:    writeTimeout,
+			disabled: writeTimeout <= 0,
+		},
+		{
+			key:      "Idle Timeout",
+			value:    idleTimeout,
+			disabled: idleTimeout <= 0,
+		},
+		{
+			key:   "Max Header Bytes",
+			value: files.FormatSizeBinary(float64(maxHeaderBytes), files.ShortestLengthPrecision),
+		},
+		{
+			key:   "Shutdown Timeout",
+			value: shutdownTimeout,
 		},
 		{
 			key:   "Log Level",
@@ -243,14 +527,52 @@ func run(cmd *cobra.Command, args []string) error {
 		{
 			key:      "TLS Cert",
 			value:    tlsCert,
-			disabled: !serveTLS,
+			disabled: !serveTLS || tlsAuto,
 		},
 		{
 			key:      "TLS Key",
 			value:    tlsKey,
+			disabled: !serveTLS || tlsAuto,
+		},
+		{
+			key:      "TLS Auto Cache",
+			value:    tlsAutoCache,
+			disabled: !tlsAuto || tlsAutoCache == "",
+		},
+		{
+			key:      "TLS Expires",
+			value:    certificateExpiry(certificate),
 			disabled: !serveTLS,
 		},
-	})
+		{
+			key:      "TLS Fingerprint",
+			value:    certificateFingerprint(certificate),
+			disabled: !serveTLS,
+		},
+		{
+			key:      "TLS Client CA",
+			value:    tlsClientCA,
+			disabled: !serveTLS || tlsClientCA == "",
+		},
+		{
+			key:      "TLS Client Read",
+			value:    strings.Join(tlsClientRead, ","),
+			disabled: !serveTLS || len(tlsClientRead) == 0,
+		},
+		{
+			key:      "TLS Client Upload",
+			value:    strings.Join(tlsClientUpload, ","),
+			disabled: !serveTLS || len(tlsClientUpload) == 0 || !writesEnabled,
+		},
+	}))
+	if err != nil {
+		return err
+	}
+
+	printlnf("")
+	printlnf("Mounts:")
+
+	err = printConfigs(mountConfigRows(mounts))
 	if err != nil {
 		return err
 	}
@@ -260,46 +582,142 @@ func run(cmd *cobra.Command, args []string) error {
 	printlnf("")
 	printlnf("Routes:")
 
-	err = registerRoutes(mux, []route{
-		{
-			pattern:     "GET /",
-			description: "Get File",
-			handler:     controller.ListFiles(),
-			disabled:    pathInfo.IsDir(),
-		},
-		{
-			pattern:     "GET /{file...}",
-			description: "List Files",
-			handler:     controller.ListFiles(),
-			disabled:    !pathInfo.IsDir(),
-		},
-		{
-			pattern:     "POST /",
-			description: "Upload File",
-			handler:     controller.UploadFile(),
-			disabled:    !uploads,
-		},
-	})
+	err = registerRoutes(mux, mountRoutes(basePath, mounts, controllers, files.NewMountsController(files.MountsControllerConfig{
+		FilesURL: basePath + "/",
+		Prefixes: mountPrefixes(mounts),
+		ACL:      aclRules,
+		Signer:   signer,
+		Version:  version,
+	})))
 	if err != nil {
 		return err
 	}
 
-	handler := logging.LogRequests(mux)
+	var handler http.Handler = mux
+
+	if !credentials.Empty() || !uploadsCredentials.Empty() || tlsClientCA != "" {
+		tusPaths := mountTusPaths(basePath, mounts)
+
+		isTusPath := func(r *http.Request) bool {
+			return slices.ContainsFunc(tusPaths, func(tusPath string) bool {
+				return strings.HasPrefix(r.URL.Path, tusPath)
+			})
+		}
+
+		handler = auth.Authenticate(handler, auth.Options{
+			Credentials:             credentials,
+			ClientIdentities:        tlsClientRead,
+			Uploads:                 uploadsCredentials,
+			UploadsClientIdentities: tlsClientUpload,
+			IsUpload:                isTusPath,
+			Skip: func(r *http.Request) bool {
+				return signer != nil &&
+					(r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+					share.HasSignature(r) &&
+					!isTusPath(r)
+			},
+		})
+	}
+
+	var (
+		packetConns []net.PacketConn
+		http3Ports  []int
+	)
+
+	if http3Enabled {
+		packetConns, err = listenPackets(listeners)
+		if err != nil {
+			return err
+		}
+
+		for _, conn := range packetConns {
+			http3Ports = append(http3Ports, conn.LocalAddr().(*net.UDPAddr).Port)
+		}
+
+		handler = altsvc.AdvertiseHTTP3(handler, altsvc.Options{
+			Ports: http3Ports,
+		})
+	}
+
+	if compressResponses {
+		handler = compress.CompressResponses(handler, compress.Options{
+			MinSize: compressMinSize,
+		})
+	}
+
+	if redirectHTTPS {
+		handler = redirect.RedirectHTTPS(handler, redirect.Options{
+			Port: httpsPort(listeners),
+		})
+	}
+
+	handler = logging.LogRequests(handler)
+
+	if len(trustedProxies) > 0 {
+		handler = proxy.ForwardedHeaders(handler, proxyOptions)
+	}
 
 	printlnf("")
-	printlnf("Serving files at %s", url)
+	for _, listener := range listeners {
+		if listener.Addr().Network() == "unix" {
+			printlnf("Serving files at %s via %s", listener.url(basePath), listenerAddress(listener))
+		} else {
+			printlnf("Serving files at %s", listener.url(basePath))
+		}
+	}
 	printlnf("")
 	printlnf("Ready to accept connections")
 	printlnf("")
 
-	if open {
+	if open && listeners[0].Addr().Network() != "unix" {
 		go func() {
-			err := browser.OpenURL(url.String())
+			err := browser.OpenURL(listeners[0].url(basePath).String())
 			if err != nil {
 				slog.Error("failed to open url in browser", "error", err)
 			}
 		}()
 	}
 
-	return http.Serve(listener, handler)
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+
+	if h2c {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetHTTP2(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	var http3Server *http3.Server
+
+	if http3Enabled {
+		http3Server = &http3.Server{
+			Handler:        handler,
+			TLSConfig:      http3.ConfigureTLSConfig(tlsConfig),
+			IdleTimeout:    idleTimeout,
+			MaxHeaderBytes: maxHeaderBytes,
+		}
+	}
+
+	return serve(ctx, server, serverListeners(listeners), serverOptions{
+		shutdownTimeout: shutdownTimeout,
+		http3Server:     http3Server,
+		packetConns:     packetConns,
+		cleanup: func() error {
+			var errs []error
+
+			for _, controller := range controllers {
+				errs = append(errs, controller.Close())
+			}
+
+			return errors.Join(errs...)
+		},
+		stop: stop,
+	})
 }
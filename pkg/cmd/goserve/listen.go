@@ -0,0 +1,331 @@
+package goserve
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	unixPrefix = "unix:"
+
+	systemdListenFDsStart = 3
+)
+
+func listen(address string, mode string) (net.Listener, error) {
+	socketPath, ok := strings.CutPrefix(address, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	if socketPath == "" {
+		return nil, fmt.Errorf("invalid unix socket address %q", address)
+	}
+
+	err := removeStaleSocket(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			listener.Close()
+
+			return nil, fmt.Errorf("invalid socket mode %q: %w", mode, err)
+		}
+
+		err = os.Chmod(socketPath, fs.FileMode(perm))
+		if err != nil {
+			listener.Close()
+
+			return nil, err
+		}
+	}
+
+	return listener, nil
+}
+
+func removeStaleSocket(socketPath string) error {
+	info, err := os.Lstat(socketPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", socketPath)
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err == nil {
+		conn.Close()
+
+		return fmt.Errorf("%s is in use", socketPath)
+	}
+
+	return os.Remove(socketPath)
+}
+
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+
+	for fd := systemdListenFDsStart; fd < systemdListenFDsStart+count; fd++ {
+		syscall.CloseOnExec(fd)
+
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+
+		listener, err := net.FileListener(file)
+
+		file.Close()
+
+		if err != nil {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+
+			return nil, fmt.Errorf("systemd socket %d: %w", fd, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+type serverListener struct {
+	net.Listener
+
+	tls        bool
+	configured bool
+}
+
+func openListeners(addresses []string, mode, defaultAddress string, defaultTLS bool) ([]serverListener, error) {
+	inherited, err := systemdListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []serverListener
+
+	for _, listener := range inherited {
+		listeners = append(listeners, serverListener{
+			Listener: listener,
+			tls:      defaultTLS && listener.Addr().Network() != "unix",
+		})
+	}
+
+	if len(listeners) > 0 {
+		if len(addresses) > 0 {
+			slog.Warn("ignoring listen addresses in favor of systemd sockets", "addresses", addresses)
+		}
+
+		return listeners, nil
+	}
+
+	if len(addresses) == 0 {
+		addresses = []string{defaultAddress}
+	}
+
+	for _, address := range addresses {
+		useTLS := defaultTLS && !strings.HasPrefix(address, unixPrefix)
+
+		switch {
+		case strings.HasPrefix(address, "https://"):
+			if !defaultTLS {
+				closeListeners(listeners)
+
+				return nil, fmt.Errorf("listen address %q requires a tls certificate", address)
+			}
+
+			useTLS = true
+
+		case strings.HasPrefix(address, "http://"):
+			useTLS = false
+		}
+
+		address = strings.TrimPrefix(strings.TrimPrefix(address, "https://"), "http://")
+
+		listener, err := listen(address, mode)
+		if err != nil {
+			closeListeners(listeners)
+
+			return nil, err
+		}
+
+		listeners = append(listeners, serverListener{
+			Listener:   listener,
+			tls:        useTLS,
+			configured: true,
+		})
+	}
+
+	return listeners, nil
+}
+
+func listenerHosts(addresses []string, listeners []serverListener) []string {
+	var hosts []string
+
+	for _, address := range addresses {
+		if strings.HasPrefix(address, unixPrefix) || strings.HasPrefix(address, "http://") {
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(strings.TrimPrefix(address, "https://"))
+		if err == nil {
+			hosts = append(hosts, host)
+		}
+	}
+
+	for _, listener := range listeners {
+		addr, ok := listener.Addr().(*net.TCPAddr)
+		if ok && listener.tls {
+			hosts = append(hosts, addr.IP.String())
+		}
+	}
+
+	return hosts
+}
+
+func listenPackets(listeners []serverListener) ([]net.PacketConn, error) {
+	var conns []net.PacketConn
+
+	for _, listener := range listeners {
+		addr, ok := listener.Addr().(*net.TCPAddr)
+		if !ok || !listener.tls {
+			continue
+		}
+
+		conn, err := net.ListenPacket("udp", addr.String())
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+
+			return nil, err
+		}
+
+		conns = append(conns, conn)
+	}
+
+	if len(conns) == 0 {
+		return nil, errors.New("http3 requires a tls tcp listener")
+	}
+
+	return conns, nil
+}
+
+func closeListeners(listeners []serverListener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
+
+func serverListeners(listeners []serverListener) []net.Listener {
+	netListeners := make([]net.Listener, 0, len(listeners))
+
+	for _, listener := range listeners {
+		netListeners = append(netListeners, listener.Listener)
+	}
+
+	return netListeners
+}
+
+func listenerConfigRows(listeners []serverListener, custom bool) []config {
+	var configs []config
+
+	for _, listener := range listeners {
+		value := listenerAddress(listener)
+
+		if listener.Addr().Network() != "unix" || listener.tls {
+			value = listener.scheme() + "://" + value
+		}
+
+		configs = append(configs, config{
+			key:      "Listen",
+			value:    value,
+			disabled: !custom,
+		})
+	}
+
+	return configs
+}
+
+func httpsPort(listeners []serverListener) int {
+	for _, listener := range listeners {
+		addr, ok := listener.Addr().(*net.TCPAddr)
+		if ok && listener.tls {
+			return addr.Port
+		}
+	}
+
+	return 0
+}
+
+func (l serverListener) scheme() string {
+	if l.tls {
+		return "https"
+	}
+
+	return "http"
+}
+
+func (l serverListener) url(basePath string) *url.URL {
+	u := &url.URL{
+		Scheme: l.scheme(),
+		Host:   "localhost",
+		Path:   basePath + "/",
+	}
+
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if ok {
+		host := "localhost"
+
+		if !addr.IP.IsUnspecified() {
+			host = addr.IP.String()
+		}
+
+		u.Host = net.JoinHostPort(host, strconv.Itoa(addr.Port))
+	}
+
+	return u
+}
+
+func listenerAddress(listener net.Listener) string {
+	addr := listener.Addr()
+
+	if addr.Network() == "unix" {
+		return unixPrefix + addr.String()
+	}
+
+	return addr.String()
+}
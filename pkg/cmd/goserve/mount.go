@@ -0,0 +1,394 @@
+package goserve
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/files"
+)
+
+type mountConfig struct {
+	Path             string  `mapstructure:"path"`
+	Prefix           string  `mapstructure:"prefix"`
+	Exclude          *string `mapstructure:"exclude"`
+	Uploads          *bool   `mapstructure:"uploads"`
+	UploadsDir       *string `mapstructure:"uploads-dir"`
+	UploadsInPlace   *bool   `mapstructure:"uploads-in-place"`
+	UploadsMaxSize   *int64  `mapstructure:"uploads-max-size"`
+	UploadsTimestamp *bool   `mapstructure:"uploads-timestamp"`
+	UploadsConflict  *string `mapstructure:"uploads-conflict"`
+	UploadsName      *string `mapstructure:"uploads-name"`
+	Put              *bool   `mapstructure:"put"`
+	Delete           *bool   `mapstructure:"delete"`
+}
+
+func parseMount(arg string) mountConfig {
+	i := strings.LastIndex(arg, ":")
+
+	if i > 0 && strings.HasPrefix(arg[i+1:], "/") {
+		return mountConfig{
+			Path:   arg[:i],
+			Prefix: arg[i+1:],
+		}
+	}
+
+	return mountConfig{
+		Path: arg,
+	}
+}
+
+func resolveMounts(mounts []mountConfig) ([]mountConfig, error) {
+	if len(mounts) == 0 {
+		return nil, errors.New("at least one {file|dir} is required")
+	}
+
+	prefixes := make(map[string]string, len(mounts))
+
+	for i := range mounts {
+		mount := &mounts[i]
+
+		if mount.Path == "" {
+			return nil, fmt.Errorf("mount %d: path is required", i)
+		}
+
+		absPath, err := filepath.Abs(mount.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		mount.Path = absPath
+
+		if mount.Prefix == "" {
+			if len(mounts) == 1 {
+				mount.Prefix = "/"
+			} else {
+				mount.Prefix = "/" + filepath.Base(mount.Path)
+			}
+		}
+
+		mount.Prefix = path.Clean("/" + mount.Prefix)
+
+		other, ok := prefixes[mount.Prefix]
+		if ok {
+			return nil, fmt.Errorf("duplicate mount prefix %q for %s and %s", mount.Prefix, other, mount.Path)
+		}
+
+		prefixes[mount.Prefix] = mount.Path
+	}
+
+	return mounts, nil
+}
+
+func mountFileSystem(mountPath string) (fs.FS, fs.FileInfo, error) {
+	pathInfo, err := os.Stat(mountPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pathInfo.IsDir() {
+		return os.DirFS(mountPath), pathInfo, nil
+	}
+
+	fileSystem, err := fs.Sub(os.DirFS(filepath.Dir(mountPath)), filepath.Base(mountPath))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fileSystem, pathInfo, nil
+}
+
+type mount struct {
+	path            string
+	prefix          string
+	pathInfo        fs.FileInfo
+	fileSystem      fs.FS
+	excludePattern  *regexp.Regexp
+	uploads         bool
+	uploadsDir      string
+	uploadsInPlace  bool
+	uploadsMaxSize  int64
+	uploadsConflict files.ConflictPolicy
+	uploadsName     string
+	put             bool
+	delete          bool
+}
+
+func loadMount(config, defaults mountConfig) (mount, error) {
+	fileSystem, pathInfo, err := mountFileSystem(config.Path)
+	if err != nil {
+		return mount{}, err
+	}
+
+	m := mount{
+		path:           config.Path,
+		prefix:         config.Prefix,
+		pathInfo:       pathInfo,
+		fileSystem:     fileSystem,
+		uploads:        valueOr(config.Uploads, defaults.Uploads),
+		uploadsDir:     valueOr(config.UploadsDir, defaults.UploadsDir),
+		uploadsInPlace: valueOr(config.UploadsInPlace, defaults.UploadsInPlace),
+		uploadsMaxSize: valueOr(config.UploadsMaxSize, defaults.UploadsMaxSize),
+		uploadsName:    valueOr(config.UploadsName, defaults.UploadsName),
+		put:            valueOr(config.Put, defaults.Put),
+		delete:         valueOr(config.Delete, defaults.Delete),
+	}
+
+	err = files.ValidateNameTemplate(m.uploadsName)
+	if err != nil {
+		return mount{}, err
+	}
+
+	if m.uploadsName == "" && valueOr(config.UploadsTimestamp, defaults.UploadsTimestamp) {
+		m.uploadsName = files.TimestampNameTemplate
+	}
+
+	m.uploadsConflict, err = files.ParseConflictPolicy(valueOr(config.UploadsConflict, defaults.UploadsConflict))
+	if err != nil {
+		return mount{}, err
+	}
+
+	exclude := valueOr(config.Exclude, defaults.Exclude)
+
+	if exclude != "" {
+		m.excludePattern, err = regexp.Compile(exclude)
+		if err != nil {
+			return mount{}, err
+		}
+	}
+
+	if m.uploads && m.uploadsInPlace && !pathInfo.IsDir() {
+		return mount{}, fmt.Errorf("in-place uploads require a directory: %s", m.path)
+	}
+
+	if (m.put || m.delete) && !pathInfo.IsDir() {
+		return mount{}, fmt.Errorf("put and delete require a directory: %s", m.path)
+	}
+
+	if m.uploads && !m.uploadsInPlace {
+		m.uploadsDir, err = prepareUploadsDir(m.uploadsDir)
+		if err != nil {
+			return mount{}, err
+		}
+	}
+
+	return m, nil
+}
+
+func prepareUploadsDir(uploadsDir string) (string, error) {
+	if uploadsDir == "" {
+		uploadsDir = os.TempDir()
+	}
+
+	uploadsDir, err := filepath.Abs(uploadsDir)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = os.Stat(uploadsDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		err = os.MkdirAll(uploadsDir, 0o750)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return uploadsDir, nil
+}
+
+func valueOr[T any](value, fallback *T) T {
+	if value != nil {
+		return *value
+	}
+
+	if fallback != nil {
+		return *fallback
+	}
+
+	var zero T
+
+	return zero
+}
+
+func mountPrefixes(mounts []mount) []string {
+	prefixes := make([]string, 0, len(mounts))
+
+	for _, mount := range mounts {
+		prefixes = append(prefixes, mount.prefix)
+	}
+
+	return prefixes
+}
+
+func mountTusPaths(basePath string, mounts []mount) []string {
+	var tusPaths []string
+
+	for _, mount := range mounts {
+		if mount.uploads {
+			tusPaths = append(tusPaths, mount.url(basePath)+files.TusPath)
+		}
+	}
+
+	return tusPaths
+}
+
+func mountConfigRows(mounts []mount) []config {
+	var configs []config
+
+	for _, mount := range mounts {
+		configs = append(configs,
+			config{
+				key:   mount.prefix,
+				value: mount.path,
+			},
+			config{
+				key:      "  Exclude Pattern",
+				value:    mount.excludePattern,
+				disabled: mount.excludePattern == nil,
+			},
+			config{
+				key:      "  Uploads Dir",
+				value:    mount.uploadsDir,
+				disabled: !mount.uploads || mount.uploadsInPlace,
+			},
+			config{
+				key:      "  Uploads In Place",
+				value:    mount.uploadsInPlace,
+				disabled: !mount.uploads || !mount.uploadsInPlace,
+			},
+			config{
+				key:      "  Uploads Max Size",
+				value:    files.FormatSizeMetric(float64(mount.uploadsMaxSize), files.ShortestLengthPrecision),
+				disabled: !mount.uploads || mount.uploadsMaxSize <= 0,
+			},
+			config{
+				key:      "  Uploads Conflict",
+				value:    mount.uploadsConflict,
+				disabled: !mount.uploads && !mount.put,
+			},
+			config{
+				key:      "  Uploads Name",
+				value:    mount.uploadsName,
+				disabled: !mount.uploads || mount.uploadsName == "",
+			},
+			config{
+				key:      "  Put",
+				value:    mount.put,
+				disabled: !mount.put,
+			},
+			config{
+				key:      "  Delete",
+				value:    mount.delete,
+				disabled: !mount.delete,
+			},
+		)
+	}
+
+	return configs
+}
+
+func mountRoutes(basePath string, mounts []mount, controllers []*files.Controller, mountsController *files.MountsController) []route {
+	var routes []route
+
+	hasRoot := false
+
+	for i, mount := range mounts {
+		controller := controllers[i]
+
+		hasRoot = hasRoot || mount.prefix == "/"
+
+		mountURL := strings.TrimSuffix(mount.url(basePath), "/")
+
+		routes = append(routes,
+			route{
+				pattern:     "GET " + mountURL + "/",
+				description: "Get File",
+				handler:     controller.ListFiles(),
+				disabled:    mount.pathInfo.IsDir(),
+			},
+			route{
+				pattern:     "GET " + mountURL + "/{file...}",
+				description: "List Files",
+				handler:     controller.ListFiles(),
+				disabled:    !mount.pathInfo.IsDir(),
+			},
+			route{
+				pattern:     "POST " + cmp.Or(mountURL, "/"),
+				description: "Upload File",
+				handler:     controller.UploadFile(),
+				disabled:    !mount.uploads || mount.uploadsInPlace,
+			},
+			route{
+				pattern:     "POST " + mountURL + "/{dir...}",
+				description: "Upload File",
+				handler:     controller.UploadFile(),
+				disabled:    !mount.uploads || !mount.uploadsInPlace,
+			},
+			route{
+				pattern:     "OPTIONS " + mountURL + "/" + files.TusPath,
+				description: "Tus Options",
+				handler:     controller.TusOptions(),
+				disabled:    !mount.uploads,
+			},
+			route{
+				pattern:     "POST " + mountURL + "/" + files.TusPath,
+				description: "Tus Create Upload",
+				handler:     controller.TusCreate(),
+				disabled:    !mount.uploads,
+			},
+			route{
+				pattern:     "HEAD " + mountURL + "/" + files.TusPath + "{id}",
+				description: "Tus Upload Status",
+				handler:     controller.TusStatus(),
+				disabled:    !mount.uploads,
+			},
+			route{
+				pattern:     "PATCH " + mountURL + "/" + files.TusPath + "{id}",
+				description: "Tus Append Upload",
+				handler:     controller.TusPatch(),
+				disabled:    !mount.uploads,
+			},
+			route{
+				pattern:     "DELETE " + mountURL + "/" + files.TusPath + "{id}",
+				description: "Tus Delete Upload",
+				handler:     controller.TusDelete(),
+				disabled:    !mount.uploads,
+			},
+			route{
+				pattern:     "PUT " + mountURL + "/{file...}",
+				description: "Put File",
+				handler:     controller.PutFile(),
+				disabled:    !mount.put,
+			},
+			route{
+				pattern:     "DELETE " + mountURL + "/{file...}",
+				description: "Delete File",
+				handler:     controller.DeleteFile(),
+				disabled:    !mount.delete,
+			},
+		)
+	}
+
+	routes = append(routes, route{
+		pattern:     "GET " + basePath + "/{$}",
+		description: "List Mounts",
+		handler:     mountsController.ListMounts(),
+		disabled:    hasRoot,
+	})
+
+	return routes
+}
+
+func (m mount) url(basePath string) string {
+	return strings.TrimSuffix(basePath+m.prefix, "/") + "/"
+}
@@ -0,0 +1,177 @@
+package goserve
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+const shutdownHandlerWait = 5 * time.Second
+
+type requestTracker struct {
+	active atomic.Int64
+	wg     sync.WaitGroup
+}
+
+func (t *requestTracker) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.active.Add(1)
+		t.wg.Add(1)
+
+		defer func() {
+			t.active.Add(-1)
+			t.wg.Done()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *requestTracker) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+type serverOptions struct {
+	shutdownTimeout time.Duration
+	http3Server     *http3.Server
+	packetConns     []net.PacketConn
+	cleanup         func() error
+	stop            func()
+}
+
+func serve(ctx context.Context, server *http.Server, listeners []net.Listener, o serverOptions) error {
+	tracker := &requestTracker{}
+
+	server.Handler = tracker.track(server.Handler)
+
+	serveErr := make(chan error, len(listeners)+len(o.packetConns))
+
+	for _, listener := range listeners {
+		go func() {
+			serveErr <- server.Serve(listener)
+		}()
+	}
+
+	if o.http3Server != nil {
+		o.http3Server.Handler = tracker.track(o.http3Server.Handler)
+
+		for _, conn := range o.packetConns {
+			go func() {
+				serveErr <- o.http3Server.Serve(conn)
+			}()
+		}
+	}
+
+	var startErr error
+
+	select {
+	case startErr = <-serveErr:
+		slog.Error("failed to serve", "error", startErr)
+
+	case <-ctx.Done():
+	}
+
+	if o.stop != nil {
+		o.stop()
+	}
+
+	start := time.Now()
+
+	inFlight := tracker.active.Load()
+
+	slog.Info("shutting down server", "in_flight", inFlight, "timeout", o.shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), o.shutdownTimeout)
+	defer cancel()
+
+	var interrupted int64
+
+	if o.http3Server != nil {
+		go o.http3Server.Shutdown(shutdownCtx)
+	}
+
+	err := server.Shutdown(shutdownCtx)
+
+	if err == nil && o.http3Server != nil {
+		deadline, _ := shutdownCtx.Deadline()
+
+		if tracker.wait(time.Until(deadline)) {
+			err = o.http3Server.Close()
+		} else {
+			err = context.DeadlineExceeded
+		}
+	}
+
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			slog.Error("failed to shut down server", "error", err)
+		}
+
+		interrupted = tracker.active.Load()
+
+		err = server.Close()
+		if err != nil {
+			slog.Error("failed to close server", "error", err)
+		}
+
+		if o.http3Server != nil {
+			err = o.http3Server.Close()
+			if err != nil {
+				slog.Error("failed to close http3 server", "error", err)
+			}
+		}
+
+		if !tracker.wait(shutdownHandlerWait) {
+			slog.Warn("requests still running after server close", "active", tracker.active.Load())
+		}
+	}
+
+	for _, conn := range o.packetConns {
+		conn.Close()
+	}
+
+	if o.cleanup != nil {
+		err = o.cleanup()
+		if err != nil {
+			slog.Error("failed to clean up", "error", err)
+		}
+	}
+
+	slog.Info("server stopped", "drained", inFlight-interrupted, "interrupted", interrupted, "duration", time.Since(start))
+
+	errs := []error{startErr}
+
+	pending := len(listeners) + len(o.packetConns)
+	if startErr != nil {
+		pending--
+	}
+
+	for range pending {
+		err = <-serveErr
+		if !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
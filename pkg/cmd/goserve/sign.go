@@ -0,0 +1,75 @@
+package goserve
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cmgsj/goserve/pkg/files"
+	"github.com/cmgsj/goserve/pkg/share"
+)
+
+func newSignCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "sign {path}",
+		Short:         "Create a share link",
+		Long:          "Create an expiring signed share link for a file or directory",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args:          cobra.ExactArgs(1),
+		RunE:          runSign,
+	}
+
+	cmd.Flags().Duration("ttl", files.DefaultShareTTL, "share link time to live")
+	cmd.Flags().String("url", "", "server base url")
+	cmd.Flags().String("user", "", "user whose access rules apply to the link")
+
+	return cmd
+}
+
+func runSign(cmd *cobra.Command, args []string) error {
+	signKey := viper.GetString("sign-key")
+
+	ttl, err := cmd.Flags().GetDuration("ttl")
+	if err != nil {
+		return err
+	}
+
+	baseURL, err := cmd.Flags().GetString("url")
+	if err != nil {
+		return err
+	}
+
+	username, err := cmd.Flags().GetString("user")
+	if err != nil {
+		return err
+	}
+
+	if signKey == "" {
+		return errors.New("sign key is required")
+	}
+
+	if ttl <= 0 {
+		return fmt.Errorf("invalid ttl %s", ttl)
+	}
+
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+
+	link.Path = path.Join("/", link.Path, args[0])
+
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+
+	link.RawQuery = share.NewSigner([]byte(signKey)).Sign(link.Path, username, expires).Encode()
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), link.String())
+
+	return err
+}
@@ -0,0 +1,209 @@
+package goserve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	autoCertificateValidity = 365 * 24 * time.Hour
+	autoCertificateRenewal  = 30 * 24 * time.Hour
+	autoCertificateFile     = "cert.pem"
+	autoKeyFile             = "key.pem"
+)
+
+func autoCertificate(cacheDir string, hosts []string) (tls.Certificate, error) {
+	if cacheDir == "" {
+		certPEM, keyPEM, err := generateCertificate(hosts)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+
+		return tls.X509KeyPair(certPEM, keyPEM)
+	}
+
+	certFile := filepath.Join(cacheDir, autoCertificateFile)
+	keyFile := filepath.Join(cacheDir, autoKeyFile)
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil && time.Until(certificate.Leaf.NotAfter) > autoCertificateRenewal && certificateCovers(certificate.Leaf, hosts) {
+		return certificate, nil
+	}
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return tls.Certificate{}, err
+	}
+
+	certPEM, keyPEM, err := generateCertificate(hosts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	err = os.MkdirAll(cacheDir, 0o700)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	err = os.WriteFile(keyFile, keyPEM, 0o600)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	err = os.WriteFile(certFile, certPEM, 0o644)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+func generateCertificate(hosts []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"goserve"},
+			CommonName:   certificateHost(hosts),
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(autoCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	template.DNSNames, template.IPAddresses = certificateSANs(hosts)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
+
+func certificateSANs(hosts []string) ([]string, []net.IP) {
+	dnsNames := []string{"localhost"}
+
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+
+	hostname, err := os.Hostname()
+	if err == nil && hostname != "" {
+		dnsNames = append(dnsNames, hostname)
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if ok && !ipNet.IP.IsLoopback() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		if host == "" || isUnspecifiedHost(host) {
+			continue
+		}
+
+		ip := net.ParseIP(host)
+
+		switch {
+		case ip != nil:
+			if !slices.ContainsFunc(ips, ip.Equal) {
+				ips = append(ips, ip)
+			}
+
+		case !slices.Contains(dnsNames, host):
+			dnsNames = append(dnsNames, host)
+		}
+	}
+
+	return dnsNames, ips
+}
+
+func certificateCovers(certificate *x509.Certificate, hosts []string) bool {
+	for _, host := range hosts {
+		if host != "" && !isUnspecifiedHost(host) && certificate.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func certificateHost(hosts []string) string {
+	for _, host := range hosts {
+		if host != "" && !isUnspecifiedHost(host) {
+			return host
+		}
+	}
+
+	return "localhost"
+}
+
+func certificateFingerprint(certificate tls.Certificate) string {
+	if len(certificate.Certificate) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(certificate.Certificate[0])
+
+	parts := make([]string, len(sum))
+
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+
+	return "SHA256 " + strings.Join(parts, ":")
+}
+
+func certificateExpiry(certificate tls.Certificate) string {
+	if certificate.Leaf == nil {
+		return ""
+	}
+
+	return certificate.Leaf.NotAfter.UTC().Format(time.DateTime)
+}
+
+func isUnspecifiedHost(host string) bool {
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsUnspecified()
+}
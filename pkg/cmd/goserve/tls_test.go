@@ -0,0 +1,76 @@
+package goserve
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestListenerHosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		hosts     []string
+	}{
+		{name: "none", addresses: nil, hosts: nil},
+		{name: "https name", addresses: []string{"https://files.example.com:8443"}, hosts: []string{"files.example.com"}},
+		{name: "bare address", addresses: []string{"files.example.com:8443"}, hosts: []string{"files.example.com"}},
+		{name: "ipv6", addresses: []string{"https://[2001:db8::1]:8443"}, hosts: []string{"2001:db8::1"}},
+		{name: "plain http", addresses: []string{"http://plain.example.com:8080"}, hosts: nil},
+		{name: "unix socket", addresses: []string{"unix:/run/goserve.sock"}, hosts: nil},
+		{
+			name:      "mixed",
+			addresses: []string{"http://plain.example.com:80", "https://a.example.com:443", "https://b.example.com:8443"},
+			hosts:     []string{"a.example.com", "b.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts := listenerHosts(tt.addresses, nil)
+			if !slices.Equal(hosts, tt.hosts) {
+				t.Errorf("listenerHosts(%q) = %q, want %q", tt.addresses, hosts, tt.hosts)
+			}
+		})
+	}
+}
+
+func TestCertificateSANs(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    []string
+		dnsNames []string
+		ips      []net.IP
+	}{
+		{name: "host", hosts: []string{"files.example.com"}, dnsNames: []string{"files.example.com"}},
+		{name: "listen hosts", hosts: []string{"localhost", "a.example.com", "b.example.com"}, dnsNames: []string{"a.example.com", "b.example.com"}},
+		{name: "ip", hosts: []string{"192.0.2.10"}, ips: []net.IP{net.ParseIP("192.0.2.10")}},
+		{name: "unspecified", hosts: []string{"0.0.0.0", "::", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dnsNames, ips := certificateSANs(tt.hosts)
+
+			for _, dnsName := range append([]string{"localhost"}, tt.dnsNames...) {
+				if !slices.Contains(dnsNames, dnsName) {
+					t.Errorf("certificateSANs(%q) dns names = %q, missing %q", tt.hosts, dnsNames, dnsName)
+				}
+			}
+
+			if slices.Contains(dnsNames, "") || slices.Contains(dnsNames, "0.0.0.0") || slices.Contains(dnsNames, "::") {
+				t.Errorf("certificateSANs(%q) dns names = %q, want no unspecified hosts", tt.hosts, dnsNames)
+			}
+
+			for _, ip := range append([]net.IP{net.IPv4(127, 0, 0, 1)}, tt.ips...) {
+				if !slices.ContainsFunc(ips, ip.Equal) {
+					t.Errorf("certificateSANs(%q) ips = %v, missing %v", tt.hosts, ips, ip)
+				}
+			}
+
+			if len(dnsNames) != len(slices.Compact(slices.Sorted(slices.Values(dnsNames)))) {
+				t.Errorf("certificateSANs(%q) dns names = %q, want no duplicates", tt.hosts, dnsNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+)
+
+const (
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+)
+
+type archiveWriter interface {
+	writeDir(name string, info fs.FileInfo) error
+	writeFile(name string, info fs.FileInfo, content io.Reader) error
+	Close() error
+}
+
+func (c *Controller) writeArchive(w http.ResponseWriter, r *http.Request, dir, format string) error {
+	var (
+		archive     archiveWriter
+		contentType string
+	)
+
+	body := &archiveBodyWriter{w: w}
+
+	switch format {
+	case ArchiveZip:
+		archive = zipArchiveWriter{zip.NewWriter(body)}
+		contentType = "application/zip"
+
+	case ArchiveTar:
+		archive = tarArchiveWriter{tar.NewWriter(body)}
+		contentType = "application/x-tar"
+
+	case ArchiveTarGz:
+		archive = newTarGzArchiveWriter(body)
+		contentType = "application/gzip"
+
+	default:
+		return fmt.Errorf("invalid archive format %q: %w", format, fs.ErrInvalid)
+	}
+
+	name := path.Base(dir)
+
+	if dir == RootDir {
+		name = "goserve"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+
+	err := fs.WalkDir(c.fileSystem, dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		permission := acl.Read
+
+		if entry.IsDir() {
+			permission = acl.List
+		}
+
+		if c.isForbidden(r, filePath, permission) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		info, err := fs.Stat(c.fileSystem, filePath)
+		if err != nil {
+			return err
+		}
+
+		relPath := filePath
+
+		if dir != RootDir {
+			relPath = strings.TrimPrefix(strings.TrimPrefix(filePath, dir), "/")
+		}
+
+		entryName := path.Join(name, relPath)
+
+		switch {
+		case entry.IsDir():
+			return archive.writeDir(entryName, info)
+
+		case info.Mode().IsRegular():
+			return c.writeArchiveFile(archive, filePath, entryName, info)
+
+		default:
+			return nil
+		}
+	})
+	if err == nil {
+		err = archive.Close()
+	}
+
+	if err != nil && body.written {
+		slog.Error("failed to write archive", "dir", dir, "format", format, "error", err)
+
+		panic(http.ErrAbortHandler)
+	}
+
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+	}
+
+	return err
+}
+
+func (c *Controller) writeArchiveFile(archive archiveWriter, filePath, name string, info fs.FileInfo) error {
+	fsFile, err := c.fileSystem.Open(filePath)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := fsFile.Close()
+		if err != nil {
+			slog.Error("failed to close archived file", "path", filePath, "error", err)
+		}
+	}()
+
+	return archive.writeFile(name, info, fsFile)
+}
+
+type archiveBodyWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (b *archiveBodyWriter) Write(p []byte) (int, error) {
+	b.written = true
+
+	return b.w.Write(p)
+}
+
+type zipArchiveWriter struct {
+	*zip.Writer
+}
+
+func (a zipArchiveWriter) writeDir(name string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = name + "/"
+
+	_, err = a.CreateHeader(header)
+
+	return err
+}
+
+func (a zipArchiveWriter) writeFile(name string, info fs.FileInfo, content io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = name
+	header.Method = zip.Deflate
+
+	writer, err := a.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, content)
+
+	return err
+}
+
+type tarArchiveWriter struct {
+	*tar.Writer
+}
+
+func (a tarArchiveWriter) writeDir(name string, info fs.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	header.Name = name + "/"
+
+	return a.WriteHeader(header)
+}
+
+func (a tarArchiveWriter) writeFile(name string, info fs.FileInfo, content io.Reader) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	header.Name = name
+
+	err = a.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(a, content)
+
+	return err
+}
+
+type tarGzArchiveWriter struct {
+	tarArchiveWriter
+
+	gzipWriter *gzip.Writer
+}
+
+func newTarGzArchiveWriter(w io.Writer) tarGzArchiveWriter {
+	gzipWriter := gzip.NewWriter(w)
+
+	return tarGzArchiveWriter{
+		tarArchiveWriter: tarArchiveWriter{tar.NewWriter(gzipWriter)},
+		gzipWriter:       gzipWriter,
+	}
+}
+
+func (a tarGzArchiveWriter) Close() error {
+	err := a.tarArchiveWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	return a.gzipWriter.Close()
+}
@@ -1,202 +1,428 @@
 package files
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+	middlewarehttp "github.com/cmgsj/goserve/pkg/middleware/http"
+	"github.com/cmgsj/goserve/pkg/share"
 )
 
 type Controller struct {
-	fileSystem  fs.FS
-	htmlHandler handler
-	jsonHandler handler
-	textHandler handler
-	config      ControllerConfig
+	fileSystem     fs.FS
+	handlers       handlers
+	contentTypes   map[string]string
+	partialUploads sync.Map
+	tusLocks       sync.Map
+	config         ControllerConfig
 }
 
 type ControllerConfig struct {
-	FilesURL         string
-	ExcludePattern   *regexp.Regexp
-	Uploads          bool
-	UploadsDir       string
-	UploadsTimestamp bool
-	Version          string
+	FilesURL             string
+	MountPath            string
+	Root                 string
+	ExcludePattern       *regexp.Regexp
+	ACL                  *acl.Rules
+	ContentTypes         map[string]string
+	Precompressed        bool
+	PrecompressedHide    bool
+	Signer               *share.Signer
+	Index                bool
+	IndexNames           []string
+	SPA                  bool
+	Uploads              bool
+	UploadsDir           string
+	UploadsInPlace       bool
+	UploadsMaxSize       int64
+	UploadsTusExpiration time.Duration
+	UploadsConflict      ConflictPolicy
+	UploadsName          string
+	Put                  bool
+	Delete               bool
+	Version              string
 }
 
 func NewController(fileSystem fs.FS, config ControllerConfig) *Controller {
 	return &Controller{
-		fileSystem:  fileSystem,
-		htmlHandler: newHTMLHandler(config.FilesURL, config.Uploads, config.Version),
-		jsonHandler: newJSONHandler(),
-		textHandler: newTextHandler(),
-		config:      config,
+		fileSystem:   fileSystem,
+		handlers:     newHandlers(config.FilesURL, config.Uploads, config.UploadsInPlace, config.Version),
+		contentTypes: normalizeContentTypes(config.ContentTypes),
+		config:       config,
 	}
 }
 
 func (c *Controller) ListFiles() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handler := c.requestHandler(r)
+		handler := c.handlers.forRequest(r)
+
+		if share.HasSignature(r) {
+			username, err := verifyShare(c.config.Signer, r)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+				return
+			}
+
+			r = r.WithContext(withSharedUser(r.Context(), username))
+		}
 
 		filePath := r.PathValue("file")
 
 		filePath = path.Clean(filePath)
 
-		if c.isForbidden(filePath) {
-			c.handleError(w, r, handler, fsNotExistError(filePath), http.StatusNotFound)
+		if c.isForbidden(r, filePath, acl.List) && c.isForbidden(r, filePath, acl.Read) {
+			handleError(w, r, handler, fsNotExistError(filePath), http.StatusNotFound)
 
 			return
 		}
 
 		fileInfo, err := fs.Stat(c.fileSystem, filePath)
 		if err != nil {
-			c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+			if c.config.SPA && errors.Is(err, fs.ErrNotExist) {
+				indexPath, indexInfo, ok := c.indexFile(r, RootDir)
+				if ok {
+					err = c.copyFile(w, r, indexPath, indexInfo)
+					if err != nil {
+						handleError(w, r, handler, err, fsErrorStatusCode(err))
+					}
+
+					return
+				}
+			}
+
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		permission := acl.Read
+
+		if fileInfo.IsDir() {
+			permission = acl.List
+		}
+
+		if c.isForbidden(r, filePath, permission) {
+			handleError(w, r, handler, fsNotExistError(filePath), http.StatusNotFound)
+
+			return
+		}
+
+		if r.URL.Query().Has("sign") {
+			if c.isForbidden(r, filePath, acl.Read) {
+				handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
+
+				return
+			}
+
+			err = c.shareFile(w, r, handler)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+			}
 
 			return
 		}
 
 		if !fileInfo.IsDir() {
-			err = c.copyFile(w, filePath)
+			err = c.copyFile(w, r, filePath, fileInfo)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+			}
+
+			return
+		}
+
+		archive := r.URL.Query().Get("archive")
+
+		if archive != "" {
+			err = c.writeArchive(w, r, filePath, archive)
 			if err != nil {
-				c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
 			}
 
 			return
 		}
 
-		files, err := c.readDir(filePath)
+		if c.config.Index || c.config.SPA {
+			indexPath, indexInfo, ok := c.indexFile(r, filePath)
+			if ok {
+				if !strings.HasSuffix(r.URL.Path, "/") {
+					redirectURL := *r.URL
+
+					redirectURL.Path += "/"
+
+					http.Redirect(w, r, redirectURL.String(), http.StatusMovedPermanently)
+
+					return
+				}
+
+				err = c.copyFile(w, r, indexPath, indexInfo)
+				if err != nil {
+					handleError(w, r, handler, err, fsErrorStatusCode(err))
+				}
+
+				return
+			}
+		}
+
+		files, err := c.readDir(r, filePath)
 		if err != nil {
-			c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
 
 			return
 		}
 
 		err = handler.handleDir(w, r, filePath, files)
 		if err != nil {
-			c.handleError(w, r, handler, err, http.StatusInternalServerError)
+			handleError(w, r, handler, err, http.StatusInternalServerError)
 		}
 	})
 }
 
 func (c *Controller) UploadFile() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		handler := c.requestHandler(r)
+		handler := c.handlers.forRequest(r)
+
+		dir := RootDir
+
+		if c.config.UploadsInPlace {
+			dir = path.Clean(r.PathValue("dir"))
+		}
 
-		if !c.config.Uploads {
-			c.handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
+		if !c.config.Uploads || c.isForbidden(r, dir, acl.Upload) {
+			handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
 
 			return
 		}
 
-		formFile, header, err := r.FormFile("file")
+		err := c.checkUploadDir(dir)
 		if err != nil {
-			c.handleError(w, r, handler, err, http.StatusBadRequest)
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
 
 			return
 		}
 
-		if c.config.UploadsTimestamp {
-			header.Filename = time.Now().UTC().Format(time.DateTime) + " " + header.Filename
+		if c.config.UploadsMaxSize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, c.config.UploadsMaxSize)
 		}
 
-		filePath := filepath.Join(c.config.UploadsDir, header.Filename)
+		reader, err := r.MultipartReader()
+		if err != nil {
+			handleError(w, r, handler, err, uploadErrorStatusCode(err))
 
-		_, err = os.Stat(filePath)
+			return
+		}
+
+		results, err := c.uploadParts(r, reader, dir)
 		if err != nil {
-			if !errors.Is(err, fs.ErrNotExist) {
-				c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+			handleError(w, r, handler, err, uploadErrorStatusCode(err))
 
-				return
+			return
+		}
+
+		code := http.StatusOK
+
+		for _, result := range results {
+			if result.err != nil {
+				code = uploadErrorStatusCode(result.err)
+
+				break
 			}
-		} else {
-			c.handleError(w, r, handler, fs.ErrExist, http.StatusBadRequest)
+		}
+
+		err = handler.handleUploads(w, r, code, dir, results)
+		if err != nil {
+			handleError(w, r, handler, err, http.StatusInternalServerError)
+		}
+	})
+}
+
+func (c *Controller) PutFile() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := c.handlers.forRequest(r)
+
+		filePath, err := cleanUploadName(r.PathValue("file"))
+		if err != nil || strings.HasSuffix(r.URL.Path, "/") {
+			handleError(w, r, handler, fmt.Errorf("invalid file name %q: %w", r.PathValue("file"), fs.ErrInvalid), http.StatusBadRequest)
 
 			return
 		}
 
-		osFile, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0o644)
-		if err != nil {
-			c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+		if !c.config.Put || c.isForbidden(r, filePath, acl.Upload) {
+			handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
 
 			return
 		}
 
-		defer func() {
-			err := osFile.Close()
-			if err != nil {
-				slog.Error("failed to close uploaded file", "path", filePath, "error", err)
-			}
-		}()
+		if c.config.UploadsMaxSize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, c.config.UploadsMaxSize)
+		}
 
-		_, err = io.Copy(osFile, formFile)
+		root, err := os.OpenRoot(c.config.Root)
 		if err != nil {
-			c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
 
 			return
 		}
 
-		err = osFile.Sync()
+		defer root.Close()
+
+		conflict := ConflictOverwrite
+
+		if c.config.UploadsConflict == ConflictVersion {
+			conflict = ConflictVersion
+		}
+
+		if r.Header.Get("If-None-Match") == "*" {
+			conflict = ConflictReject
+		}
+
+		_, err = root.Stat(filepath.FromSlash(filePath))
+		created := errors.Is(err, fs.ErrNotExist)
+
+		_, _, err = c.writeUpload(root, filepath.FromSlash(filePath), r.Body, conflict)
 		if err != nil {
-			c.handleError(w, r, handler, err, fsErrorStatusCode(err))
+			code := uploadErrorStatusCode(err)
+
+			if conflict == ConflictReject && errors.Is(err, fs.ErrExist) {
+				code = http.StatusPreconditionFailed
+			}
+
+			handleError(w, r, handler, err, code)
+
+			return
+		}
+
+		if !created {
+			w.WriteHeader(http.StatusNoContent)
 
 			return
 		}
 
-		http.Redirect(w, r, c.config.FilesURL, http.StatusFound)
+		w.Header().Set("Location", (&url.URL{Path: r.URL.Path}).EscapedPath())
+		w.WriteHeader(http.StatusCreated)
 	})
 }
 
-func (c *Controller) requestHandler(r *http.Request) handler {
-	switch r.URL.Query().Get("content") {
-	case "html":
-		return c.htmlHandler
+func (c *Controller) DeleteFile() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := c.handlers.forRequest(r)
 
-	case "json":
-		return c.jsonHandler
+		filePath := path.Clean(r.PathValue("file"))
 
-	case "text", "plain":
-		return c.textHandler
-	}
+		if !c.config.Delete || filePath == RootDir {
+			handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
 
-	switch r.Header.Get("Content-Type") {
-	case "text/html":
-		return c.htmlHandler
+			return
+		}
 
-	case "application/json":
-		return c.jsonHandler
+		root, err := os.OpenRoot(c.config.Root)
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
 
-	case "text/plain":
-		return c.textHandler
-	}
+			return
+		}
 
-	return c.htmlHandler
-}
+		defer root.Close()
 
-func (c *Controller) isForbidden(filePath string) bool {
-	if filePath == RootDir {
-		return false
-	}
+		fileInfo, err := root.Lstat(filepath.FromSlash(filePath))
+		if err != nil {
+			handleError(w, r, handler, fsNotExistError(filePath), http.StatusNotFound)
+
+			return
+		}
 
-	if c.config.ExcludePattern != nil {
+		if c.isForbidden(r, filePath, acl.Delete) {
+			handleError(w, r, handler, fsNotExistError(filePath), http.StatusNotFound)
+
+			return
+		}
+
+		if fileInfo.IsDir() {
+			entries, err := fs.ReadDir(c.fileSystem, filePath)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+				return
+			}
+
+			if len(entries) > 0 {
+				handleError(w, r, handler, fmt.Errorf("directory %s is not empty", filePath), http.StatusConflict)
+
+				return
+			}
+		}
+
+		err = root.Remove(filepath.FromSlash(filePath))
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *Controller) isForbidden(r *http.Request, filePath string, permission acl.Permission) bool {
+	if filePath != RootDir {
 		for _, part := range strings.Split(filePath, "/") {
-			if c.config.ExcludePattern.MatchString(part) {
+			if isUploadTemp(part) {
+				return true
+			}
+
+			if c.config.ExcludePattern != nil && c.config.ExcludePattern.MatchString(part) {
 				return true
 			}
 		}
 	}
 
-	return false
+	username, _ := auth.User(r.Context())
+
+	return !c.config.ACL.Allowed(username, path.Join("/", c.config.MountPath, filePath), permission)
 }
 
-func (c *Controller) copyFile(dst io.Writer, filePath string) error {
+func (c *Controller) copyFile(w http.ResponseWriter, r *http.Request, filePath string, fileInfo fs.FileInfo) error {
+	contentType := c.contentType(fileInfo.Name())
+
+	if c.config.Precompressed {
+		middlewarehttp.AddVary(w.Header(), "Accept-Encoding")
+
+		encoding, encodedPath, encodedInfo := c.precompressedFile(r, filePath)
+
+		if encoding != "" {
+			if contentType == "" {
+				var err error
+
+				contentType, err = c.sniffContentType(filePath)
+				if err != nil {
+					return err
+				}
+			}
+
+			w.Header().Set("Content-Encoding", encoding)
+			w.Header().Set("Content-Length", strconv.FormatInt(encodedInfo.Size(), 10))
+
+			filePath = encodedPath
+			fileInfo = encodedInfo
+		}
+	}
+
 	fsFile, err := c.fileSystem.Open(filePath)
 	if err != nil {
 		return err
@@ -209,17 +435,50 @@ func (c *Controller) copyFile(dst io.Writer, filePath string) error {
 		}
 	}()
 
-	_, err = io.Copy(dst, fsFile)
+	w.Header().Set("ETag", fileETag(fileInfo))
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	content, ok := fsFile.(io.ReadSeeker)
+	if !ok {
+		var sniff [512]byte
+
+		n, err := io.ReadFull(fsFile, sniff[:])
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if contentType == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(sniff[:n]))
+		}
+
+		w.Header().Set("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
+		w.Header().Set("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
 
-	return err
+		_, err = io.Copy(w, io.MultiReader(bytes.NewReader(sniff[:n]), fsFile))
+
+		return err
+	}
+
+	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), content)
+
+	return nil
 }
 
-func (c *Controller) readDir(filePath string) ([]File, error) {
+func (c *Controller) readDir(r *http.Request, filePath string) ([]File, error) {
 	entries, err := fs.ReadDir(c.fileSystem, filePath)
 	if err != nil {
 		return nil, err
 	}
 
+	var fileNames map[string]struct{}
+
+	if c.config.PrecompressedHide {
+		fileNames = precompressedSources(entries)
+	}
+
 	var files []File
 
 	if filePath != RootDir {
@@ -233,7 +492,17 @@ func (c *Controller) readDir(filePath string) ([]File, error) {
 	for _, entry := range entries {
 		entryPath := path.Join(filePath, entry.Name())
 
-		if c.isForbidden(entryPath) {
+		permission := acl.Read
+
+		if entry.IsDir() {
+			permission = acl.List
+		}
+
+		if c.isForbidden(r, entryPath, permission) {
+			continue
+		}
+
+		if c.config.PrecompressedHide && isPrecompressedVariant(fileNames, entry) {
 			continue
 		}
 
@@ -259,16 +528,3 @@ func (c *Controller) readDir(filePath string) ([]File, error) {
 
 	return files, nil
 }
-
-func (c *Controller) handleError(w http.ResponseWriter, r *http.Request, handler handler, err error, code int) {
-	slog.Error("an error occurred", "error", err)
-
-	w.WriteHeader(code)
-
-	herr := handler.handleError(w, r, err, code)
-	if herr != nil {
-		slog.Error("failed to handle error", "error", herr)
-
-		fmt.Fprintln(w, err.Error())
-	}
-}
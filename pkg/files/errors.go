@@ -12,13 +12,21 @@ func fsNotExistError(filePath string) error {
 }
 
 func fsErrorStatusCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+
 	switch {
 	case err == nil:
 		return http.StatusOK
 
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+
 	case errors.Is(err, fs.ErrInvalid):
 		return http.StatusBadRequest
 
+	case errors.Is(err, fs.ErrExist):
+		return http.StatusConflict
+
 	case errors.Is(err, fs.ErrPermission):
 		return http.StatusForbidden
 
@@ -29,3 +37,21 @@ func fsErrorStatusCode(err error) int {
 		return http.StatusInternalServerError
 	}
 }
+
+func uploadErrorStatusCode(err error) int {
+	code := fsErrorStatusCode(err)
+
+	if code == http.StatusInternalServerError {
+		return http.StatusBadRequest
+	}
+
+	return code
+}
+
+func tusLockErrorStatusCode(err error) int {
+	if errors.Is(err, errTusLocked) {
+		return http.StatusLocked
+	}
+
+	return fsErrorStatusCode(err)
+}
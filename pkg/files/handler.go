@@ -1,8 +1,67 @@
 package files
 
-import "net/http"
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
 
 type handler interface {
 	handleDir(w http.ResponseWriter, r *http.Request, dir string, files []File) error
+	handleShare(w http.ResponseWriter, r *http.Request, link ShareLink) error
+	handleUploads(w http.ResponseWriter, r *http.Request, code int, dir string, results []UploadResult) error
 	handleError(w http.ResponseWriter, r *http.Request, err error, code int) error
 }
+
+type handlers struct {
+	html handler
+	json handler
+	text handler
+}
+
+func newHandlers(filesURL string, uploads, uploadsInPlace bool, version string) handlers {
+	return handlers{
+		html: newHTMLHandler(filesURL, uploads, uploadsInPlace, version),
+		json: newJSONHandler(),
+		text: newTextHandler(),
+	}
+}
+
+func (h handlers) forRequest(r *http.Request) handler {
+	switch r.URL.Query().Get("content") {
+	case "html":
+		return h.html
+
+	case "json":
+		return h.json
+
+	case "text", "plain":
+		return h.text
+	}
+
+	switch r.Header.Get("Content-Type") {
+	case "text/html":
+		return h.html
+
+	case "application/json":
+		return h.json
+
+	case "text/plain":
+		return h.text
+	}
+
+	return h.html
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, handler handler, err error, code int) {
+	slog.Error("an error occurred", "error", err)
+
+	w.WriteHeader(code)
+
+	herr := handler.handleError(w, r, err, code)
+	if herr != nil {
+		slog.Error("failed to handle error", "error", herr)
+
+		fmt.Fprintln(w, err.Error())
+	}
+}
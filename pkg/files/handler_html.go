@@ -1,11 +1,18 @@
 package files
 
 import (
+	"cmp"
 	_ "embed"
+	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"path"
 	"strings"
+
+	"github.com/cmgsj/goserve/pkg/middleware/proxy"
+	"github.com/cmgsj/goserve/pkg/share"
 )
 
 var (
@@ -15,11 +22,16 @@ var (
 )
 
 type indexParams struct {
-	FilesURL string
-	Uploads  bool
-	Version  string
-	Data     *indexDataParams
-	Error    *indexErrorParams
+	FilesURL  string
+	UploadURL string
+	UploadDir string
+	TusURL    string
+	Uploads   bool
+	Version   string
+	Query     template.URL
+	Data      *indexDataParams
+	Share     *ShareLink
+	Error     *indexErrorParams
 }
 
 type indexDataParams struct {
@@ -33,16 +45,18 @@ type indexErrorParams struct {
 }
 
 type htmlHandler struct {
-	filesURL string
-	uploads  bool
-	version  string
+	filesURL       string
+	uploads        bool
+	uploadsInPlace bool
+	version        string
 }
 
-func newHTMLHandler(filesURL string, uploads bool, version string) htmlHandler {
+func newHTMLHandler(filesURL string, uploads, uploadsInPlace bool, version string) htmlHandler {
 	return htmlHandler{
-		filesURL: strings.TrimSuffix(filesURL, "/"),
-		uploads:  uploads,
-		version:  version,
+		filesURL:       strings.TrimSuffix(filesURL, "/"),
+		uploads:        uploads,
+		uploadsInPlace: uploadsInPlace,
+		version:        version,
 	}
 }
 
@@ -62,7 +76,29 @@ func (h htmlHandler) handleDir(w http.ResponseWriter, r *http.Request, dir strin
 		}
 	}
 
-	return h.handle(w, indexParams{
+	var query template.URL
+
+	if share.HasSignature(r) {
+		query = template.URL(share.Query(r).Encode())
+	}
+
+	var uploadURL, uploadDir string
+
+	if h.uploadsInPlace {
+		uploadPath := proxy.Prefix(r.Context()) + h.filesURL + "/"
+
+		if dir != RootDir {
+			uploadPath += dir + "/"
+			uploadDir = dir
+		}
+
+		uploadURL = (&url.URL{Path: uploadPath}).EscapedPath()
+	}
+
+	return h.handle(w, r, indexParams{
+		UploadURL: uploadURL,
+		UploadDir: uploadDir,
+		Query:     query,
 		Data: &indexDataParams{
 			Breadcrumbs: breadcrumbs,
 			Files:       files,
@@ -70,8 +106,40 @@ func (h htmlHandler) handleDir(w http.ResponseWriter, r *http.Request, dir strin
 	})
 }
 
+func (h htmlHandler) handleShare(w http.ResponseWriter, r *http.Request, link ShareLink) error {
+	return h.handle(w, r, indexParams{
+		Share: &link,
+	})
+}
+
+func (h htmlHandler) handleUploads(w http.ResponseWriter, r *http.Request, code int, dir string, results []UploadResult) error {
+	if code != http.StatusOK {
+		var errs []error
+
+		for _, result := range results {
+			if result.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.err))
+			}
+		}
+
+		w.WriteHeader(code)
+
+		return h.handleError(w, r, errors.Join(errs...), code)
+	}
+
+	redirectURL := h.filesURL + "/"
+
+	if dir != RootDir {
+		redirectURL += dir + "/"
+	}
+
+	http.Redirect(w, r, (&url.URL{Path: redirectURL}).EscapedPath(), http.StatusFound)
+
+	return nil
+}
+
 func (h htmlHandler) handleError(w http.ResponseWriter, r *http.Request, err error, code int) error {
-	return h.handle(w, indexParams{
+	return h.handle(w, r, indexParams{
 		Error: &indexErrorParams{
 			Status:  http.StatusText(code),
 			Message: err.Error(),
@@ -79,8 +147,14 @@ func (h htmlHandler) handleError(w http.ResponseWriter, r *http.Request, err err
 	})
 }
 
-func (h htmlHandler) handle(w http.ResponseWriter, params indexParams) error {
-	params.FilesURL = h.filesURL
+func (h htmlHandler) handle(w http.ResponseWriter, r *http.Request, params indexParams) error {
+	params.FilesURL = proxy.Prefix(r.Context()) + h.filesURL
+
+	if params.UploadURL == "" {
+		params.UploadURL = cmp.Or(params.FilesURL, "/")
+	}
+
+	params.TusURL = (&url.URL{Path: params.FilesURL + "/" + TusPath}).EscapedPath()
 
 	params.Uploads = h.uploads
 
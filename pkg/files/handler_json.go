@@ -15,6 +15,16 @@ func (h jsonHandler) handleDir(w http.ResponseWriter, r *http.Request, dir strin
 	return h.handle(w, r, files)
 }
 
+func (h jsonHandler) handleShare(w http.ResponseWriter, r *http.Request, link ShareLink) error {
+	return h.handle(w, r, link)
+}
+
+func (h jsonHandler) handleUploads(w http.ResponseWriter, r *http.Request, code int, dir string, results []UploadResult) error {
+	w.WriteHeader(code)
+
+	return h.handle(w, r, results)
+}
+
 func (h jsonHandler) handleError(w http.ResponseWriter, r *http.Request, err error, code int) error {
 	return h.handle(w, r, map[string]any{
 		"status":  http.StatusText(code),
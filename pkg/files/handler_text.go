@@ -45,6 +45,40 @@ func (h textHandler) handleDir(w http.ResponseWriter, r *http.Request, dir strin
 	return tab.Flush()
 }
 
+func (h textHandler) handleShare(w http.ResponseWriter, r *http.Request, link ShareLink) error {
+	_, err := fmt.Fprintln(w, link.URL)
+
+	return err
+}
+
+func (h textHandler) handleUploads(w http.ResponseWriter, r *http.Request, code int, dir string, results []UploadResult) error {
+	w.WriteHeader(code)
+
+	var buf bytes.Buffer
+
+	for _, result := range results {
+		buf.WriteString(result.Name)
+		buf.WriteByte('\t')
+
+		if result.Error != "" {
+			buf.WriteString(result.Error)
+		} else {
+			buf.WriteString(result.Size)
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	tab := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+
+	_, err := buf.WriteTo(tab)
+	if err != nil {
+		return err
+	}
+
+	return tab.Flush()
+}
+
 func (h textHandler) handleError(w http.ResponseWriter, r *http.Request, err error, code int) error {
 	_, err = fmt.Fprintf(w, "%s\n\n%s\n", http.StatusText(code), err.Error())
 
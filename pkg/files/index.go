@@ -0,0 +1,36 @@
+package files
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+)
+
+var DefaultIndexNames = []string{"index.html", "index.htm"}
+
+func (c *Controller) indexFile(r *http.Request, dir string) (string, fs.FileInfo, bool) {
+	indexNames := c.config.IndexNames
+
+	if len(indexNames) == 0 {
+		indexNames = DefaultIndexNames
+	}
+
+	for _, name := range indexNames {
+		indexPath := path.Join(dir, name)
+
+		if c.isForbidden(r, indexPath, acl.Read) {
+			continue
+		}
+
+		info, err := fs.Stat(c.fileSystem, indexPath)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		return indexPath, info, true
+	}
+
+	return "", nil, false
+}
@@ -0,0 +1,85 @@
+package files
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+var defaultContentTypes = map[string]string{
+	".cjs":         "text/javascript; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".md":          "text/markdown; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".svg":         "image/svg+xml",
+	".toml":        "application/toml",
+	".wasm":        "application/wasm",
+	".webmanifest": "application/manifest+json",
+	".yaml":        "application/yaml",
+	".yml":         "application/yaml",
+}
+
+func normalizeContentTypes(contentTypes map[string]string) map[string]string {
+	normalized := make(map[string]string, len(contentTypes))
+
+	for ext, contentType := range contentTypes {
+		ext = strings.ToLower(ext)
+
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+
+		normalized[ext] = contentType
+	}
+
+	return normalized
+}
+
+func (c *Controller) contentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+
+	if ext == "" {
+		return ""
+	}
+
+	contentType, ok := c.contentTypes[ext]
+	if ok {
+		return contentType
+	}
+
+	contentType, ok = defaultContentTypes[ext]
+	if ok {
+		return contentType
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+func (c *Controller) sniffContentType(filePath string) (string, error) {
+	fsFile, err := c.fileSystem.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		err := fsFile.Close()
+		if err != nil {
+			slog.Error("failed to close sniffed file", "path", filePath, "error", err)
+		}
+	}()
+
+	var sniff [512]byte
+
+	n, err := io.ReadFull(fsFile, sniff[:])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(sniff[:n]), nil
+}
@@ -0,0 +1,72 @@
+package files
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+	"github.com/cmgsj/goserve/pkg/share"
+)
+
+type MountsController struct {
+	handlers handlers
+	config   MountsControllerConfig
+}
+
+type MountsControllerConfig struct {
+	FilesURL string
+	Prefixes []string
+	ACL      *acl.Rules
+	Signer   *share.Signer
+	Version  string
+}
+
+func NewMountsController(config MountsControllerConfig) *MountsController {
+	return &MountsController{
+		handlers: newHandlers(config.FilesURL, false, false, config.Version),
+		config:   config,
+	}
+}
+
+func (c *MountsController) ListMounts() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := c.handlers.forRequest(r)
+
+		if share.HasSignature(r) {
+			username, err := verifyShare(c.config.Signer, r)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+				return
+			}
+
+			r = r.WithContext(withSharedUser(r.Context(), username))
+		}
+
+		username, _ := auth.User(r.Context())
+
+		var files []File
+
+		for _, prefix := range c.config.Prefixes {
+			if !c.config.ACL.Allowed(username, prefix, acl.List) {
+				continue
+			}
+
+			name := strings.Trim(prefix, "/")
+
+			files = append(files, File{
+				Path:  name,
+				Name:  name,
+				IsDir: true,
+			})
+		}
+
+		Sort(files)
+
+		err := handler.handleDir(w, r, RootDir, files)
+		if err != nil {
+			handleError(w, r, handler, err, http.StatusInternalServerError)
+		}
+	})
+}
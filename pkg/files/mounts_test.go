@@ -0,0 +1,74 @@
+package files
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+	"github.com/cmgsj/goserve/pkg/share"
+)
+
+func TestListMountsShare(t *testing.T) {
+	signer := share.NewSigner([]byte("key"))
+
+	rules := &acl.Rules{
+		Rules: []acl.Rule{
+			{Path: "/private/", Principals: []string{"user:alice"}, Permissions: []acl.Permission{acl.List}},
+		},
+	}
+
+	expires := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		status  int
+		private bool
+	}{
+		{name: "no link", status: http.StatusOK},
+		{name: "anonymous link", query: signer.Sign("/", "", expires), status: http.StatusOK},
+		{name: "user link", query: signer.Sign("/", "alice", expires), status: http.StatusOK, private: true},
+		{name: "expired link", query: signer.Sign("/", "alice", time.Now().Add(-time.Hour)), status: http.StatusForbidden},
+		{name: "other path", query: signer.Sign("/public/", "alice", expires), status: http.StatusForbidden},
+		{
+			name: "tampered user",
+			query: func() url.Values {
+				values := signer.Sign("/", "", expires)
+				values.Set(share.UserParam, "alice")
+
+				return values
+			}(),
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NewMountsController(MountsControllerConfig{
+				FilesURL: "/",
+				Prefixes: []string{"/private/", "/public/"},
+				ACL:      rules,
+				Signer:   signer,
+			})
+
+			r := httptest.NewRequest("GET", "/?"+tt.query.Encode(), nil)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+
+			controller.ListMounts().ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.status == http.StatusOK && strings.Contains(w.Body.String(), "private") != tt.private {
+				t.Errorf("body = %s, want private listed %v", w.Body.String(), tt.private)
+			}
+		})
+	}
+}
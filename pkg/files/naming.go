@@ -0,0 +1,237 @@
+package files
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cmgsj/goserve/pkg/middleware/proxy"
+)
+
+type ConflictPolicy string
+
+const (
+	ConflictReject    ConflictPolicy = "reject"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictRename    ConflictPolicy = "rename"
+	ConflictVersion   ConflictPolicy = "version"
+)
+
+const TimestampNameTemplate = "{timestamp} {name}"
+
+var (
+	namePlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+	namePlaceholders       = []string{"name", "base", "ext", "date", "time", "datetime", "uuid", "ip"}
+)
+
+func ParseConflictPolicy(policy string) (ConflictPolicy, error) {
+	switch conflict := ConflictPolicy(policy); conflict {
+	case ConflictReject, ConflictOverwrite, ConflictRename, ConflictVersion:
+		return conflict, nil
+
+	default:
+		return "", fmt.Errorf("invalid uploads conflict policy %q", policy)
+	}
+}
+
+func ValidateNameTemplate(template string) error {
+	for _, placeholder := range namePlaceholderPattern.FindAllString(template, -1) {
+		if !slices.Contains(namePlaceholders, strings.Trim(placeholder, "{}")) {
+			return fmt.Errorf("invalid uploads name placeholder %q", placeholder)
+		}
+	}
+
+	return nil
+}
+
+func (c *Controller) uploadName(r *http.Request, name string) (string, error) {
+	if c.config.UploadsName == "" {
+		return name, nil
+	}
+
+	nameDir, nameFile := path.Split(name)
+
+	ext := path.Ext(nameFile)
+
+	now := time.Now().UTC()
+
+	clientIP := proxy.ClientIP(r)
+
+	ip := "unknown"
+
+	if clientIP.IsValid() {
+		ip = strings.ReplaceAll(clientIP.String(), ":", "-")
+	}
+
+	values := map[string]string{
+		"name":      nameFile,
+		"base":      strings.TrimSuffix(nameFile, ext),
+		"ext":       ext,
+		"date":      now.Format(time.DateOnly),
+		"time":      now.Format("150405"),
+		"datetime":  now.Format("20060102T150405Z"),
+		"uuid":      newUUID(),
+		"ip":        ip,
+		"timestamp": now.Format(time.DateTime),
+	}
+
+	expanded := namePlaceholderPattern.ReplaceAllStringFunc(c.config.UploadsName, func(placeholder string) string {
+		return values[strings.Trim(placeholder, "{}")]
+	})
+
+	return cleanUploadName(nameDir + expanded)
+}
+
+func checkUploadConflict(root *os.Root, name string, conflict ConflictPolicy) error {
+	nameInfo, err := root.Lstat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case conflict == ConflictRename:
+		return nil
+
+	case nameInfo.IsDir():
+		return fs.ErrExist
+
+	case conflict == ConflictOverwrite, conflict == ConflictVersion:
+		return nil
+
+	default:
+		return fs.ErrExist
+	}
+}
+
+func placeUpload(root *os.Root, tempName, name string, conflict ConflictPolicy) (string, error) {
+	err := checkUploadConflict(root, name, conflict)
+	if err != nil {
+		return "", err
+	}
+
+	switch conflict {
+	case ConflictOverwrite:
+		err = root.Rename(tempName, name)
+		if err != nil {
+			return "", err
+		}
+
+		return name, nil
+
+	case ConflictVersion:
+		err = versionUpload(root, name)
+		if err != nil {
+			return "", err
+		}
+
+		err = root.Rename(tempName, name)
+		if err != nil {
+			return "", err
+		}
+
+		return name, nil
+
+	case ConflictRename:
+		candidate := name
+
+		for n := 1; ; n++ {
+			err = linkUpload(root, tempName, candidate)
+			if !errors.Is(err, fs.ErrExist) {
+				break
+			}
+
+			candidate = renamedUploadName(name, n)
+		}
+
+		name = candidate
+
+	default:
+		err = linkUpload(root, tempName, name)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	removeUpload(root, tempName)
+
+	return name, nil
+}
+
+func versionUpload(root *os.Root, name string) error {
+	for n := 1; ; n++ {
+		err := linkUpload(root, name, versionedUploadName(name, n))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+}
+
+func linkUpload(root *os.Root, oldName, newName string) error {
+	err := root.Link(oldName, newName)
+	if err == nil || errors.Is(err, fs.ErrExist) || errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	slog.Debug("failed to link upload, copying instead", "path", filepath.Join(root.Name(), newName), "error", err)
+
+	oldFile, err := root.Open(oldName)
+	if err != nil {
+		return err
+	}
+
+	defer oldFile.Close()
+
+	newFile, err := root.OpenFile(newName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, uploadFileMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = copyUpload(newFile, oldFile)
+	if err != nil {
+		removeUpload(root, newName)
+
+		return err
+	}
+
+	return nil
+}
+
+func renamedUploadName(name string, n int) string {
+	ext := filepath.Ext(name)
+
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+func versionedUploadName(name string, n int) string {
+	return fmt.Sprintf("%s.v%d", name, n)
+}
+
+func newUUID() string {
+	var uuid [16]byte
+
+	rand.Read(uuid[:])
+
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
@@ -0,0 +1,271 @@
+package files
+
+import (
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		ok     bool
+	}{
+		{policy: "reject", ok: true},
+		{policy: "overwrite", ok: true},
+		{policy: "rename", ok: true},
+		{policy: "version", ok: true},
+		{policy: "", ok: false},
+		{policy: "Rename", ok: false},
+		{policy: "skip", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			conflict, err := ParseConflictPolicy(tt.policy)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ParseConflictPolicy(%q) error = %v, want ok %v", tt.policy, err, tt.ok)
+			}
+
+			if tt.ok && string(conflict) != tt.policy {
+				t.Errorf("ParseConflictPolicy(%q) = %q", tt.policy, conflict)
+			}
+		})
+	}
+}
+
+func TestValidateNameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		ok       bool
+	}{
+		{template: "", ok: true},
+		{template: "fixed.txt", ok: true},
+		{template: "{datetime} {name}", ok: true},
+		{template: TimestampNameTemplate, ok: false},
+		{template: "{base}-{uuid}{ext}", ok: true},
+		{template: "{ip}/{date}/{time}-{name}", ok: true},
+		{template: "{user}-{name}", ok: false},
+		{template: "{}{name}", ok: false},
+		{template: "{Name}", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			err := ValidateNameTemplate(tt.template)
+			if (err == nil) != tt.ok {
+				t.Errorf("ValidateNameTemplate(%q) error = %v, want ok %v", tt.template, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUploadName(t *testing.T) {
+	tests := []struct {
+		name       string
+		template   string
+		uploadName string
+		remoteAddr string
+		pattern    string
+		ok         bool
+	}{
+		{name: "no template", uploadName: "docs/a.tar.gz", pattern: `^docs/a\.tar\.gz$`, ok: true},
+		{name: "name", template: "{name}", uploadName: "a.txt", pattern: `^a\.txt$`, ok: true},
+		{name: "base and ext", template: "{base}-copy{ext}", uploadName: "a.tar.gz", pattern: `^a\.tar-copy\.gz$`, ok: true},
+		{name: "keeps dir", template: "{base}-copy{ext}", uploadName: "docs/a.txt", pattern: `^docs/a-copy\.txt$`, ok: true},
+		{name: "timestamp", template: TimestampNameTemplate, uploadName: "a.txt", pattern: `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} a\.txt$`, ok: true},
+		{name: "datetime", template: "{datetime}-{name}", uploadName: "a.txt", pattern: `^\d{8}T\d{6}Z-a\.txt$`, ok: true},
+		{name: "date and time", template: "{date}/{time}{ext}", uploadName: "a.txt", pattern: `^\d{4}-\d{2}-\d{2}/\d{6}\.txt$`, ok: true},
+		{name: "uuid", template: "{uuid}{ext}", uploadName: "a.txt", pattern: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.txt$`, ok: true},
+		{name: "ipv4", template: "{ip}/{name}", uploadName: "a.txt", remoteAddr: "192.0.2.1:1234", pattern: `^192\.0\.2\.1/a\.txt$`, ok: true},
+		{name: "ipv6", template: "{ip}/{name}", uploadName: "a.txt", remoteAddr: "[2001:db8::1]:1234", pattern: `^2001-db8--1/a\.txt$`, ok: true},
+		{name: "unix socket", template: "{ip}/{name}", uploadName: "a.txt", remoteAddr: "@", pattern: `^unknown/a\.txt$`, ok: true},
+		{name: "parent dir", template: "../{name}", uploadName: "a.txt", pattern: `^a\.txt$`, ok: true},
+		{name: "empty", template: "{ext}", uploadName: "a", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(nil, ControllerConfig{UploadsName: tt.template})
+
+			r := httptest.NewRequest("POST", "/", nil)
+
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+
+			name, err := c.uploadName(r, tt.uploadName)
+			if (err == nil) != tt.ok {
+				t.Fatalf("uploadName(%q) error = %v, want ok %v", tt.uploadName, err, tt.ok)
+			}
+
+			if tt.ok && !regexp.MustCompile(tt.pattern).MatchString(name) {
+				t.Errorf("uploadName(%q) = %q, want match for %s", tt.uploadName, name, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestPlaceUpload(t *testing.T) {
+	tests := []struct {
+		name     string
+		conflict ConflictPolicy
+		existing []string
+		dir      bool
+		placed   string
+		err      error
+		files    map[string]string
+	}{
+		{
+			name:     "reject new",
+			conflict: ConflictReject,
+			placed:   "a.txt",
+			files:    map[string]string{"a.txt": "new"},
+		},
+		{
+			name:     "reject existing",
+			conflict: ConflictReject,
+			existing: []string{"a.txt"},
+			err:      fs.ErrExist,
+			files:    map[string]string{"a.txt": "old a.txt"},
+		},
+		{
+			name:     "overwrite existing",
+			conflict: ConflictOverwrite,
+			existing: []string{"a.txt"},
+			placed:   "a.txt",
+			files:    map[string]string{"a.txt": "new"},
+		},
+		{
+			name:     "overwrite dir",
+			conflict: ConflictOverwrite,
+			dir:      true,
+			err:      fs.ErrExist,
+		},
+		{
+			name:     "rename new",
+			conflict: ConflictRename,
+			placed:   "a.txt",
+			files:    map[string]string{"a.txt": "new"},
+		},
+		{
+			name:     "rename existing",
+			conflict: ConflictRename,
+			existing: []string{"a.txt", "a (1).txt"},
+			placed:   "a (2).txt",
+			files:    map[string]string{"a.txt": "old a.txt", "a (1).txt": "old a (1).txt", "a (2).txt": "new"},
+		},
+		{
+			name:     "rename dir",
+			conflict: ConflictRename,
+			dir:      true,
+			placed:   "a (1).txt",
+			files:    map[string]string{"a (1).txt": "new"},
+		},
+		{
+			name:     "version new",
+			conflict: ConflictVersion,
+			placed:   "a.txt",
+			files:    map[string]string{"a.txt": "new"},
+		},
+		{
+			name:     "version existing",
+			conflict: ConflictVersion,
+			existing: []string{"a.txt", "a.txt.v1"},
+			placed:   "a.txt",
+			files:    map[string]string{"a.txt": "new", "a.txt.v1": "old a.txt.v1", "a.txt.v2": "old a.txt"},
+		},
+		{
+			name:     "version dir",
+			conflict: ConflictVersion,
+			dir:      true,
+			err:      fs.ErrExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for _, name := range tt.existing {
+				err := os.WriteFile(filepath.Join(dir, name), []byte("old "+name), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.dir {
+				err := os.Mkdir(filepath.Join(dir, "a.txt"), 0o750)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := os.WriteFile(filepath.Join(dir, "upload.tmp"), []byte("new"), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root, err := os.OpenRoot(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer root.Close()
+
+			placed, err := placeUpload(root, "upload.tmp", "a.txt", tt.conflict)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("placeUpload() error = %v, want %v", err, tt.err)
+			}
+
+			if placed != tt.placed {
+				t.Errorf("placeUpload() = %q, want %q", placed, tt.placed)
+			}
+
+			if tt.err == nil {
+				_, err = os.Stat(filepath.Join(dir, "upload.tmp"))
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("temp file was not removed: %v", err)
+				}
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, entry := range entries {
+				if entry.IsDir() || entry.Name() == "upload.tmp" {
+					continue
+				}
+
+				content, ok := tt.files[entry.Name()]
+				if !ok {
+					t.Errorf("unexpected file %q", entry.Name())
+
+					continue
+				}
+
+				got, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(got) != content {
+					t.Errorf("%s = %q, want %q", entry.Name(), got, content)
+				}
+			}
+
+			for name := range tt.files {
+				if !slices.ContainsFunc(entries, func(entry os.DirEntry) bool { return entry.Name() == name }) {
+					t.Errorf("missing file %q", name)
+				}
+			}
+		})
+	}
+}
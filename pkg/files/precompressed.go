@@ -0,0 +1,96 @@
+package files
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+	middlewarehttp "github.com/cmgsj/goserve/pkg/middleware/http"
+)
+
+type precompressedEncoding struct {
+	encoding string
+	ext      string
+}
+
+var precompressedEncodings = []precompressedEncoding{
+	{encoding: "zstd", ext: ".zst"},
+	{encoding: "br", ext: ".br"},
+	{encoding: "gzip", ext: ".gz"},
+}
+
+func (c *Controller) precompressedFile(r *http.Request, filePath string) (string, string, fs.FileInfo) {
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+
+	if acceptEncoding == "" {
+		return "", "", nil
+	}
+
+	var encodings []string
+
+	infos := make(map[string]fs.FileInfo)
+
+	for _, precompressed := range precompressedEncodings {
+		encodedPath := filePath + precompressed.ext
+
+		if c.isForbidden(r, encodedPath, acl.Read) {
+			continue
+		}
+
+		info, err := fs.Stat(c.fileSystem, encodedPath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		encodings = append(encodings, precompressed.encoding)
+
+		infos[precompressed.encoding] = info
+	}
+
+	encoding := middlewarehttp.NegotiateEncoding(acceptEncoding, encodings)
+
+	if encoding == "" {
+		return "", "", nil
+	}
+
+	for _, precompressed := range precompressedEncodings {
+		if precompressed.encoding == encoding {
+			return encoding, filePath + precompressed.ext, infos[encoding]
+		}
+	}
+
+	return "", "", nil
+}
+
+func precompressedSources(entries []fs.DirEntry) map[string]struct{} {
+	fileNames := make(map[string]struct{}, len(entries))
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			fileNames[entry.Name()] = struct{}{}
+		}
+	}
+
+	return fileNames
+}
+
+func isPrecompressedVariant(fileNames map[string]struct{}, entry fs.DirEntry) bool {
+	if entry.IsDir() {
+		return false
+	}
+
+	for _, precompressed := range precompressedEncodings {
+		name, ok := strings.CutSuffix(entry.Name(), precompressed.ext)
+		if !ok || name == "" {
+			continue
+		}
+
+		_, ok = fileNames[name]
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
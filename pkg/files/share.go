@@ -0,0 +1,77 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+	"github.com/cmgsj/goserve/pkg/middleware/proxy"
+	"github.com/cmgsj/goserve/pkg/share"
+)
+
+const DefaultShareTTL = 24 * time.Hour
+
+type ShareLink struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+func verifyShare(signer *share.Signer, r *http.Request) (string, error) {
+	if signer == nil {
+		return "", fmt.Errorf("%w: %w", share.ErrInvalidSignature, fs.ErrPermission)
+	}
+
+	username, err := signer.Verify(r, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", err, fs.ErrPermission)
+	}
+
+	return username, nil
+}
+
+func (c *Controller) shareFile(w http.ResponseWriter, r *http.Request, handler handler) error {
+	if c.config.Signer == nil || share.HasSignature(r) {
+		return fmt.Errorf("share links disabled: %w", fs.ErrPermission)
+	}
+
+	ttl := DefaultShareTTL
+
+	value := r.URL.Query().Get("sign")
+
+	if value != "" {
+		var err error
+
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid share ttl %q: %w", value, fs.ErrInvalid)
+		}
+	}
+
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+
+	username, _ := auth.User(r.Context())
+
+	link := url.URL{
+		Scheme:   proxy.Scheme(r),
+		Host:     r.Host,
+		Path:     proxy.Prefix(r.Context()) + r.URL.Path,
+		RawQuery: c.config.Signer.Sign(r.URL.Path, username, expires).Encode(),
+	}
+
+	return handler.handleShare(w, r, ShareLink{
+		URL:     link.String(),
+		Expires: expires,
+	})
+}
+
+func withSharedUser(ctx context.Context, username string) context.Context {
+	if username == "" {
+		return ctx
+	}
+
+	return auth.WithUser(ctx, username)
+}
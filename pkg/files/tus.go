@@ -0,0 +1,625 @@
+package files
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+)
+
+const (
+	TusVersion           = "1.0.0"
+	TusPath              = "uploads/tus/"
+	DefaultTusExpiration = 24 * time.Hour
+
+	tusExtensions  = "creation,termination,expiration"
+	tusContentType = "application/offset+octet-stream"
+	tusStateDir    = ".goserve-tus"
+	tusIDLength    = 26
+)
+
+var (
+	errTusVersion     = errors.New("unsupported tus version")
+	errTusContentType = errors.New("unsupported tus content type")
+	errTusOffset      = errors.New("upload offset mismatch")
+	errTusLocked      = errors.New("upload in progress")
+)
+
+type tusUpload struct {
+	Name     string    `json:"name"`
+	Length   int64     `json:"length"`
+	Metadata string    `json:"metadata,omitempty"`
+	User     string    `json:"user,omitempty"`
+	Expires  time.Time `json:"expires"`
+}
+
+func (c *Controller) TusOptions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", TusVersion)
+		w.Header().Set("Tus-Version", TusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+
+		if c.config.UploadsMaxSize > 0 {
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(c.config.UploadsMaxSize, 10))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *Controller) TusCreate() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := c.tusRequest(w, r)
+		if !ok {
+			return
+		}
+
+		upload, dir, err := c.newTusUpload(r)
+		if err != nil {
+			handleError(w, r, handler, err, uploadErrorStatusCode(err))
+
+			return
+		}
+
+		if c.isForbidden(r, dir, acl.Upload) || (c.config.UploadsInPlace && c.isForbidden(r, upload.Name, acl.Upload)) {
+			handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
+
+			return
+		}
+
+		err = c.checkUploadDir(dir)
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		root, err := c.uploadsBaseRoot()
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		defer root.Close()
+
+		c.expireTusUploads(root)
+
+		id, err := c.createTusUpload(root, upload)
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		if upload.Length == 0 {
+			err = c.finishTusUpload(root, id, upload)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+				return
+			}
+		}
+
+		w.Header().Set("Location", c.config.FilesURL+TusPath+id)
+		w.Header().Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	})
+}
+
+func (c *Controller) TusStatus() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := c.tusRequest(w, r)
+		if !ok {
+			return
+		}
+
+		root, err := c.uploadsBaseRoot()
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		defer root.Close()
+
+		upload, offset, err := c.loadTusUpload(r, root, r.PathValue("id"))
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		w.Header().Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+
+		if upload.Metadata != "" {
+			w.Header().Set("Upload-Metadata", upload.Metadata)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (c *Controller) TusPatch() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := c.tusRequest(w, r)
+		if !ok {
+			return
+		}
+
+		if r.Header.Get("Content-Type") != tusContentType {
+			handleError(w, r, handler, errTusContentType, http.StatusUnsupportedMediaType)
+
+			return
+		}
+
+		id := r.PathValue("id")
+
+		root, err := c.uploadsBaseRoot()
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		defer root.Close()
+
+		unlock, err := c.lockTusUpload(root, id)
+		if err != nil {
+			handleError(w, r, handler, err, tusLockErrorStatusCode(err))
+
+			return
+		}
+
+		defer unlock()
+
+		upload, offset, err := c.loadTusUpload(r, root, id)
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		requestOffset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || requestOffset != offset {
+			handleError(w, r, handler, errTusOffset, http.StatusConflict)
+
+			return
+		}
+
+		offset, copyErr := appendTusUpload(root, id, r.Body, upload.Length-offset)
+
+		upload.Expires = time.Now().Add(c.tusExpiration()).UTC().Truncate(time.Second)
+
+		err = saveTusUpload(root, id, upload)
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		if copyErr != nil {
+			handleError(w, r, handler, copyErr, uploadErrorStatusCode(copyErr))
+
+			return
+		}
+
+		if offset == upload.Length {
+			err = c.finishTusUpload(root, id, upload)
+			if err != nil {
+				handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+				return
+			}
+		}
+
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		w.Header().Set("Upload-Expires", upload.Expires.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *Controller) TusDelete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := c.tusRequest(w, r)
+		if !ok {
+			return
+		}
+
+		id := r.PathValue("id")
+
+		root, err := c.uploadsBaseRoot()
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		defer root.Close()
+
+		unlock, err := c.lockTusUpload(root, id)
+		if err != nil {
+			handleError(w, r, handler, err, tusLockErrorStatusCode(err))
+
+			return
+		}
+
+		defer unlock()
+
+		_, _, err = c.loadTusUpload(r, root, id)
+		if err != nil {
+			handleError(w, r, handler, err, fsErrorStatusCode(err))
+
+			return
+		}
+
+		c.removeTusUpload(root, id)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (c *Controller) tusRequest(w http.ResponseWriter, r *http.Request) (handler, bool) {
+	handler := c.handlers.forRequest(r)
+
+	w.Header().Set("Tus-Resumable", TusVersion)
+
+	if !c.config.Uploads {
+		handleError(w, r, handler, fs.ErrPermission, http.StatusForbidden)
+
+		return nil, false
+	}
+
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+
+		handleError(w, r, handler, errTusVersion, http.StatusPreconditionFailed)
+
+		return nil, false
+	}
+
+	return handler, true
+}
+
+func (c *Controller) newTusUpload(r *http.Request) (tusUpload, string, error) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return tusUpload{}, "", fmt.Errorf("invalid upload length: %w", fs.ErrInvalid)
+	}
+
+	if c.config.UploadsMaxSize > 0 && length > c.config.UploadsMaxSize {
+		return tusUpload{}, "", &http.MaxBytesError{Limit: c.config.UploadsMaxSize}
+	}
+
+	rawMetadata := r.Header.Get("Upload-Metadata")
+
+	metadata, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		return tusUpload{}, "", err
+	}
+
+	name, err := cleanUploadName(metadata["filename"])
+	if err != nil {
+		return tusUpload{}, "", err
+	}
+
+	name, err = c.uploadName(r, name)
+	if err != nil {
+		return tusUpload{}, "", err
+	}
+
+	dir := RootDir
+
+	if c.config.UploadsInPlace {
+		dir = path.Clean(metadata["dir"])
+
+		if !fs.ValidPath(dir) {
+			return tusUpload{}, "", fmt.Errorf("invalid upload dir %q: %w", metadata["dir"], fs.ErrInvalid)
+		}
+
+		name = path.Join(dir, name)
+	}
+
+	username, _ := auth.User(r.Context())
+
+	return tusUpload{
+		Name:     name,
+		Length:   length,
+		Metadata: rawMetadata,
+		User:     username,
+		Expires:  time.Now().Add(c.tusExpiration()).UTC().Truncate(time.Second),
+	}, dir, nil
+}
+
+func (c *Controller) createTusUpload(root *os.Root, upload tusUpload) (string, error) {
+	err := checkUploadConflict(root, filepath.FromSlash(upload.Name), c.config.UploadsConflict)
+	if err != nil {
+		return "", err
+	}
+
+	err = root.MkdirAll(tusStateDir, 0o750)
+	if err != nil {
+		return "", err
+	}
+
+	id := rand.Text()
+
+	dataFile, err := root.OpenFile(tusDataPath(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, uploadFileMode)
+	if err != nil {
+		return "", err
+	}
+
+	err = dataFile.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = saveTusUpload(root, id, upload)
+	if err != nil {
+		c.removeTusUpload(root, id)
+
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (c *Controller) loadTusUpload(r *http.Request, root *os.Root, id string) (tusUpload, int64, error) {
+	if !validTusID(id) {
+		return tusUpload{}, 0, tusNotExistError(id)
+	}
+
+	content, err := root.ReadFile(tusInfoPath(id))
+	if err != nil {
+		return tusUpload{}, 0, tusNotExistError(id)
+	}
+
+	var upload tusUpload
+
+	err = json.Unmarshal(content, &upload)
+	if err != nil {
+		return tusUpload{}, 0, err
+	}
+
+	username, _ := auth.User(r.Context())
+
+	if upload.User != username {
+		return tusUpload{}, 0, tusNotExistError(id)
+	}
+
+	if time.Now().After(upload.Expires) {
+		c.removeTusUpload(root, id)
+
+		return tusUpload{}, 0, tusNotExistError(id)
+	}
+
+	dataInfo, err := root.Stat(tusDataPath(id))
+	if err != nil {
+		return tusUpload{}, 0, err
+	}
+
+	return upload, dataInfo.Size(), nil
+}
+
+func (c *Controller) finishTusUpload(root *os.Root, id string, upload tusUpload) error {
+	name := filepath.FromSlash(upload.Name)
+
+	err := root.MkdirAll(filepath.Dir(name), 0o750)
+	if err != nil {
+		return err
+	}
+
+	_, err = placeUpload(root, tusDataPath(id), name, c.config.UploadsConflict)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			c.removeTusUpload(root, id)
+		}
+
+		return err
+	}
+
+	c.removeTusUpload(root, id)
+
+	return nil
+}
+
+func (c *Controller) expireTusUploads(root *os.Root) {
+	dir, err := root.Open(tusStateDir)
+	if err != nil {
+		return
+	}
+
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	for _, name := range names {
+		id, ok := strings.CutSuffix(name, ".json")
+		if !ok || !validTusID(id) {
+			continue
+		}
+
+		content, err := root.ReadFile(tusInfoPath(id))
+		if err != nil {
+			continue
+		}
+
+		var upload tusUpload
+
+		err = json.Unmarshal(content, &upload)
+		if err == nil && !now.After(upload.Expires) {
+			continue
+		}
+
+		unlock, err := c.lockTusUpload(root, id)
+		if err != nil {
+			continue
+		}
+
+		slog.Info("removing expired upload", "id", id, "name", upload.Name)
+
+		c.removeTusUpload(root, id)
+
+		unlock()
+	}
+}
+
+func (c *Controller) lockTusUpload(root *os.Root, id string) (func(), error) {
+	if !validTusID(id) || !tusUploadExists(root, id) {
+		return nil, tusNotExistError(id)
+	}
+
+	value, _ := c.tusLocks.LoadOrStore(id, &sync.Mutex{})
+
+	mu, _ := value.(*sync.Mutex)
+
+	if !mu.TryLock() {
+		return nil, errTusLocked
+	}
+
+	if !tusUploadExists(root, id) {
+		c.tusLocks.Delete(id)
+
+		mu.Unlock()
+
+		return nil, tusNotExistError(id)
+	}
+
+	return mu.Unlock, nil
+}
+
+func (c *Controller) tusExpiration() time.Duration {
+	if c.config.UploadsTusExpiration > 0 {
+		return c.config.UploadsTusExpiration
+	}
+
+	return DefaultTusExpiration
+}
+
+func appendTusUpload(root *os.Root, id string, content io.Reader, remaining int64) (int64, error) {
+	dataFile, err := root.OpenFile(tusDataPath(id), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+
+	_, copyErr := io.Copy(dataFile, io.LimitReader(content, remaining))
+
+	err = dataFile.Sync()
+	if err != nil {
+		dataFile.Close()
+
+		return 0, err
+	}
+
+	dataInfo, err := dataFile.Stat()
+	if err != nil {
+		dataFile.Close()
+
+		return 0, err
+	}
+
+	err = dataFile.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	return dataInfo.Size(), copyErr
+}
+
+func saveTusUpload(root *os.Root, id string, upload tusUpload) error {
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	return root.WriteFile(tusInfoPath(id), content, 0o600)
+}
+
+func (c *Controller) removeTusUpload(root *os.Root, id string) {
+	for _, name := range []string{tusDataPath(id), tusInfoPath(id)} {
+		err := root.Remove(name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Error("failed to remove upload state", "path", filepath.Join(root.Name(), name), "error", err)
+		}
+	}
+
+	c.tusLocks.Delete(id)
+}
+
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	for pair := range strings.SplitSeq(header, ",") {
+		pair = strings.TrimSpace(pair)
+
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload metadata %q: %w", key, fs.ErrInvalid)
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+func validTusID(id string) bool {
+	if len(id) != tusIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if (r < 'A' || r > 'Z') && (r < '2' || r > '7') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func tusUploadExists(root *os.Root, id string) bool {
+	_, err := root.Lstat(tusInfoPath(id))
+
+	return err == nil
+}
+
+func tusDataPath(id string) string {
+	return filepath.Join(tusStateDir, id)
+}
+
+func tusInfoPath(id string) string {
+	return filepath.Join(tusStateDir, id+".json")
+}
+
+func tusNotExistError(id string) error {
+	return fmt.Errorf("upload %s: %w", id, fs.ErrNotExist)
+}
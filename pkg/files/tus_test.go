@@ -0,0 +1,513 @@
+package files
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
+)
+
+func newTusTestHandler(t *testing.T, config ControllerConfig) (*Controller, http.Handler, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	config.FilesURL = "/"
+	config.Root = dir
+	config.UploadsDir = dir
+	config.Uploads = true
+
+	controller := NewController(os.DirFS(dir), config)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /"+TusPath, controller.TusCreate())
+	mux.Handle("HEAD /"+TusPath+"{id}", controller.TusStatus())
+	mux.Handle("PATCH /"+TusPath+"{id}", controller.TusPatch())
+	mux.Handle("DELETE /"+TusPath+"{id}", controller.TusDelete())
+
+	return controller, mux, dir
+}
+
+func tusRequest(method, target string, header map[string]string, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	r.Header.Set("Tus-Resumable", TusVersion)
+
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+
+	return r
+}
+
+func serveTus(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestTusResumeWithUploadCredentials(t *testing.T) {
+	_, handler, dir := newTusTestHandler(t, ControllerConfig{})
+
+	credentials := auth.NewCredentials()
+
+	err := credentials.AddUser("reader:secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := auth.NewCredentials()
+
+	err = uploads.AddUser("uploader:secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler = auth.Authenticate(handler, auth.Options{
+		Credentials: credentials,
+		Uploads:     uploads,
+		IsUpload: func(r *http.Request) bool {
+			return strings.HasPrefix(r.URL.Path, "/"+TusPath)
+		},
+	})
+
+	withUploader := func(r *http.Request) *http.Request {
+		r.SetBasicAuth("uploader", "secret")
+
+		return r
+	}
+
+	w := serveTus(handler, withUploader(tusRequest(http.MethodPost, "/"+TusPath, map[string]string{
+		"Upload-Length":   "6",
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("resumed.txt")),
+	}, "")))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	location := w.Header().Get("Location")
+
+	w = serveTus(handler, withUploader(tusRequest(http.MethodPatch, location, map[string]string{
+		"Content-Type":  tusContentType,
+		"Upload-Offset": "0",
+	}, "abc")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("patch status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	r := tusRequest(http.MethodHead, location, nil, "")
+	r.SetBasicAuth("reader", "secret")
+
+	w = serveTus(handler, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("head with read credentials status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w = serveTus(handler, withUploader(tusRequest(http.MethodHead, location, nil, "")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("head status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	offset := w.Header().Get("Upload-Offset")
+	if offset != "3" {
+		t.Fatalf("Upload-Offset = %q, want %q", offset, "3")
+	}
+
+	w = serveTus(handler, withUploader(tusRequest(http.MethodPatch, location, map[string]string{
+		"Content-Type":  tusContentType,
+		"Upload-Offset": offset,
+	}, "def")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("resume status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "resumed.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "abcdef" {
+		t.Errorf("content = %q, want %q", content, "abcdef")
+	}
+}
+
+func TestTusLocksRemoved(t *testing.T) {
+	controller, handler, _ := newTusTestHandler(t, ControllerConfig{})
+
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		body   string
+		status int
+	}{
+		{
+			name:   "finish",
+			method: http.MethodPatch,
+			header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "0"},
+			body:   "abc",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			status: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveTus(handler, tusRequest(http.MethodPost, "/"+TusPath, map[string]string{
+				"Upload-Length":   "3",
+				"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte(tt.name+".txt")),
+			}, ""))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("create status = %d, want %d", w.Code, http.StatusCreated)
+			}
+
+			location := w.Header().Get("Location")
+
+			w = serveTus(handler, tusRequest(tt.method, location, tt.header, tt.body))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			_, ok := controller.tusLocks.Load(strings.TrimPrefix(location, "/"+TusPath))
+			if ok {
+				t.Error("upload lock was not removed")
+			}
+		})
+	}
+}
+
+func TestTusCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   map[string]string
+		status   int
+		existing string
+		created  string
+	}{
+		{
+			name:   "valid",
+			header: map[string]string{"Upload-Length": "6", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt"))},
+			status: http.StatusCreated,
+		},
+		{
+			name:    "empty",
+			header:  map[string]string{"Upload-Length": "0", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("empty.txt"))},
+			status:  http.StatusCreated,
+			created: "empty.txt",
+		},
+		{
+			name:   "unsupported version",
+			header: map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "6", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt"))},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			name:   "missing length",
+			header: map[string]string{"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt"))},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "negative length",
+			header: map[string]string{"Upload-Length": "-1", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt"))},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "too large",
+			header: map[string]string{"Upload-Length": "11", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt"))},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "invalid metadata",
+			header: map[string]string{"Upload-Length": "6", "Upload-Metadata": "filename !"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing filename",
+			header: map[string]string{"Upload-Length": "6"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:     "existing file",
+			header:   map[string]string{"Upload-Length": "6", "Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("exists.txt"))},
+			status:   http.StatusConflict,
+			existing: "exists.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler, dir := newTusTestHandler(t, ControllerConfig{UploadsMaxSize: 10})
+
+			if tt.existing != "" {
+				err := os.WriteFile(filepath.Join(dir, tt.existing), nil, 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := serveTus(handler, tusRequest(http.MethodPost, "/"+TusPath, tt.header, ""))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if w.Code == http.StatusCreated && !strings.HasPrefix(w.Header().Get("Location"), "/"+TusPath) {
+				t.Errorf("Location = %q, want prefix %q", w.Header().Get("Location"), "/"+TusPath)
+			}
+
+			if tt.created != "" {
+				_, err := os.Stat(filepath.Join(dir, tt.created))
+				if err != nil {
+					t.Errorf("upload was not created: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTusPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		header  map[string]string
+		body    string
+		status  int
+		offset  string
+		content string
+	}{
+		{
+			name:    "resume",
+			header:  map[string]string{"Content-Type": tusContentType, "Upload-Offset": "3"},
+			body:    "def",
+			status:  http.StatusNoContent,
+			offset:  "6",
+			content: "abcdef",
+		},
+		{
+			name:    "partial resume",
+			header:  map[string]string{"Content-Type": tusContentType, "Upload-Offset": "3"},
+			body:    "d",
+			status:  http.StatusNoContent,
+			offset:  "4",
+			content: "",
+		},
+		{
+			name:    "body beyond length",
+			header:  map[string]string{"Content-Type": tusContentType, "Upload-Offset": "3"},
+			body:    "defghi",
+			status:  http.StatusNoContent,
+			offset:  "6",
+			content: "abcdef",
+		},
+		{
+			name:   "stale offset",
+			header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "0"},
+			body:   "abc",
+			status: http.StatusConflict,
+			offset: "3",
+		},
+		{
+			name:   "offset ahead",
+			header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "4"},
+			body:   "ef",
+			status: http.StatusConflict,
+			offset: "3",
+		},
+		{
+			name:   "invalid offset",
+			header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "three"},
+			body:   "def",
+			status: http.StatusConflict,
+			offset: "3",
+		},
+		{
+			name:   "missing offset",
+			header: map[string]string{"Content-Type": tusContentType},
+			body:   "def",
+			status: http.StatusConflict,
+			offset: "3",
+		},
+		{
+			name:   "unsupported content type",
+			header: map[string]string{"Content-Type": "text/plain", "Upload-Offset": "3"},
+			body:   "def",
+			status: http.StatusUnsupportedMediaType,
+			offset: "3",
+		},
+		{
+			name:   "unsupported version",
+			header: map[string]string{"Tus-Resumable": "0.2.2", "Content-Type": tusContentType, "Upload-Offset": "3"},
+			body:   "def",
+			status: http.StatusPreconditionFailed,
+			offset: "3",
+		},
+		{
+			name:   "unknown upload",
+			id:     strings.Repeat("A", tusIDLength),
+			header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "3"},
+			body:   "def",
+			status: http.StatusNotFound,
+			offset: "3",
+		},
+		{
+			name:   "invalid id",
+			id:     "not-an-upload",
+			header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "3"},
+			body:   "def",
+			status: http.StatusNotFound,
+			offset: "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler, dir := newTusTestHandler(t, ControllerConfig{})
+
+			w := serveTus(handler, tusRequest(http.MethodPost, "/"+TusPath, map[string]string{
+				"Upload-Length":   "6",
+				"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt")),
+			}, ""))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("create status = %d, want %d", w.Code, http.StatusCreated)
+			}
+
+			location := w.Header().Get("Location")
+
+			w = serveTus(handler, tusRequest(http.MethodPatch, location, map[string]string{
+				"Content-Type":  tusContentType,
+				"Upload-Offset": "0",
+			}, "abc"))
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("first patch status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+
+			target := location
+
+			if tt.id != "" {
+				target = "/" + TusPath + tt.id
+			}
+
+			w = serveTus(handler, tusRequest(http.MethodPatch, target, tt.header, tt.body))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.content != "" {
+				content, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(content) != tt.content {
+					t.Errorf("content = %q, want %q", content, tt.content)
+				}
+
+				return
+			}
+
+			w = serveTus(handler, tusRequest(http.MethodHead, location, nil, ""))
+			if w.Code != http.StatusOK {
+				t.Fatalf("head status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			offset := w.Header().Get("Upload-Offset")
+			if offset != tt.offset {
+				t.Errorf("Upload-Offset = %q, want %q", offset, tt.offset)
+			}
+		})
+	}
+}
+
+func TestTusCreateInPlaceForbidden(t *testing.T) {
+	rules := &acl.Rules{
+		Rules: []acl.Rule{
+			{Path: "/private/**", Principals: []string{"group:ops"}, Permissions: []acl.Permission{acl.Upload}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		dir      string
+		status   int
+	}{
+		{name: "allowed", filename: "public.txt", status: http.StatusCreated},
+		{name: "acl in file name", filename: "private/evil.txt", status: http.StatusForbidden},
+		{name: "acl in dir", filename: "evil.txt", dir: "private", status: http.StatusForbidden},
+		{name: "excluded in file name", filename: ".git/hooks/post-commit", status: http.StatusForbidden},
+		{name: "upload state in file name", filename: tusStateDir + "/state.json", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler, dir := newTusTestHandler(t, ControllerConfig{
+				UploadsInPlace: true,
+				ACL:            rules,
+				ExcludePattern: regexp.MustCompile(`^\.git$`),
+			})
+
+			metadata := "filename " + base64.StdEncoding.EncodeToString([]byte(tt.filename))
+
+			if tt.dir != "" {
+				metadata += ",dir " + base64.StdEncoding.EncodeToString([]byte(tt.dir))
+			}
+
+			w := serveTus(handler, tusRequest(http.MethodPost, "/"+TusPath, map[string]string{
+				"Upload-Length":   "0",
+				"Upload-Metadata": metadata,
+			}, ""))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.status != http.StatusCreated {
+				_, err := os.Stat(filepath.Join(dir, path.Join(tt.dir, tt.filename)))
+				if err == nil {
+					t.Errorf("forbidden upload %q was written", tt.filename)
+				}
+			}
+		})
+	}
+}
+
+func TestTusLocksUnknownUploads(t *testing.T) {
+	controller, handler, _ := newTusTestHandler(t, ControllerConfig{})
+
+	tests := []struct {
+		name   string
+		method string
+		id     string
+		header map[string]string
+	}{
+		{name: "patch unknown", method: http.MethodPatch, id: strings.Repeat("A", tusIDLength), header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "0"}},
+		{name: "patch invalid", method: http.MethodPatch, id: "made-up", header: map[string]string{"Content-Type": tusContentType, "Upload-Offset": "0"}},
+		{name: "delete unknown", method: http.MethodDelete, id: strings.Repeat("B", tusIDLength)},
+		{name: "delete invalid", method: http.MethodDelete, id: "made-up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveTus(handler, tusRequest(tt.method, "/"+TusPath+tt.id, tt.header, "abc"))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+
+			_, ok := controller.tusLocks.Load(tt.id)
+			if ok {
+				t.Errorf("lock stored for unknown upload %q", tt.id)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/acl"
+)
+
+const (
+	uploadFormName    = "file"
+	uploadTempPattern = ".goserve-upload-*"
+	uploadFileMode    = 0o644
+)
+
+type UploadResult struct {
+	Name  string `json:"name"`
+	Size  string `json:"size,omitempty"`
+	Error string `json:"error,omitempty"`
+
+	err error
+}
+
+func (c *Controller) uploadParts(r *http.Request, reader *multipart.Reader, dir string) ([]UploadResult, error) {
+	root, err := c.uploadsRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	defer root.Close()
+
+	var results []UploadResult
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, err
+		}
+
+		if part.FormName() != uploadFormName {
+			continue
+		}
+
+		result, err := c.uploadPart(r, root, dir, part)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, http.ErrMissingFile
+	}
+
+	return results, nil
+}
+
+func (c *Controller) checkUploadDir(dir string) error {
+	if !c.config.UploadsInPlace {
+		return nil
+	}
+
+	dirInfo, err := fs.Stat(c.fileSystem, dir)
+	if err != nil {
+		return err
+	}
+
+	if !dirInfo.IsDir() {
+		return fmt.Errorf("%s is not a directory: %w", dir, fs.ErrInvalid)
+	}
+
+	return nil
+}
+
+func (c *Controller) uploadsRoot(dir string) (*os.Root, error) {
+	root, err := c.uploadsBaseRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == RootDir {
+		return root, nil
+	}
+
+	defer root.Close()
+
+	return root.OpenRoot(filepath.FromSlash(dir))
+}
+
+func (c *Controller) uploadsBaseRoot() (*os.Root, error) {
+	if c.config.UploadsInPlace {
+		return os.OpenRoot(c.config.Root)
+	}
+
+	return os.OpenRoot(c.config.UploadsDir)
+}
+
+func (c *Controller) uploadPart(r *http.Request, root *os.Root, dir string, part *multipart.Part) (UploadResult, error) {
+	name, err := uploadFileName(part)
+	if err != nil {
+		return uploadFailure(name, err), nil
+	}
+
+	name, err = c.uploadName(r, name)
+	if err != nil {
+		return uploadFailure(name, err), nil
+	}
+
+	if c.config.UploadsInPlace && c.isForbidden(r, path.Join(dir, name), acl.Upload) {
+		return uploadFailure(name, fs.ErrPermission), nil
+	}
+
+	uploadedName, size, err := c.writeUpload(root, filepath.FromSlash(name), part, c.config.UploadsConflict)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+
+		if errors.As(err, &maxBytesErr) {
+			return UploadResult{}, err
+		}
+
+		return uploadFailure(name, err), nil
+	}
+
+	return UploadResult{
+		Name: filepath.ToSlash(uploadedName),
+		Size: FormatSizeMetric(float64(size), ShortestLengthPrecision),
+	}, nil
+}
+
+func uploadFailure(name string, err error) UploadResult {
+	return UploadResult{
+		Name:  name,
+		Error: err.Error(),
+		err:   err,
+	}
+}
+
+func uploadFileName(part *multipart.Part) (string, error) {
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil {
+		return "", fmt.Errorf("invalid content disposition: %w", fs.ErrInvalid)
+	}
+
+	return cleanUploadName(params["filename"])
+}
+
+func cleanUploadName(name string) (string, error) {
+	cleanName := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+
+	if cleanName == "" || !filepath.IsLocal(filepath.FromSlash(cleanName)) {
+		return name, fmt.Errorf("invalid file name %q: %w", name, fs.ErrInvalid)
+	}
+
+	if slices.ContainsFunc(strings.Split(cleanName, "/"), isUploadTemp) {
+		return name, fmt.Errorf("reserved file name %q: %w", name, fs.ErrPermission)
+	}
+
+	return cleanName, nil
+}
+
+func (c *Controller) writeUpload(root *os.Root, name string, content io.Reader, conflict ConflictPolicy) (string, int64, error) {
+	err := checkUploadConflict(root, name, conflict)
+	if err != nil {
+		return "", 0, err
+	}
+
+	dir := filepath.Dir(name)
+
+	err = root.MkdirAll(dir, 0o750)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tempFile, tempName, err := createUploadTemp(root, dir)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tempPath := filepath.Join(root.Name(), tempName)
+
+	c.partialUploads.Store(tempPath, struct{}{})
+
+	defer c.partialUploads.Delete(tempPath)
+
+	size, err := copyUpload(tempFile, content)
+	if err != nil {
+		removeUpload(root, tempName)
+
+		return "", 0, err
+	}
+
+	name, err = placeUpload(root, tempName, name, conflict)
+	if err != nil {
+		removeUpload(root, tempName)
+
+		return "", 0, err
+	}
+
+	return name, size, nil
+}
+
+func createUploadTemp(root *os.Root, dir string) (*os.File, string, error) {
+	for {
+		tempName := filepath.Join(dir, strings.Replace(uploadTempPattern, "*", strconv.FormatUint(rand.Uint64(), 36), 1))
+
+		tempFile, err := root.OpenFile(tempName, os.O_RDWR|os.O_CREATE|os.O_EXCL, uploadFileMode)
+		if err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				continue
+			}
+
+			return nil, "", err
+		}
+
+		return tempFile, tempName, nil
+	}
+}
+
+func (c *Controller) Close() error {
+	var errs []error
+
+	c.partialUploads.Range(func(key, _ any) bool {
+		tempPath, _ := key.(string)
+
+		slog.Warn("removing partial upload", "path", tempPath)
+
+		err := os.Remove(tempPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+
+		c.partialUploads.Delete(key)
+
+		return true
+	})
+
+	return errors.Join(errs...)
+}
+
+func copyUpload(osFile *os.File, content io.Reader) (int64, error) {
+	size, err := io.Copy(osFile, content)
+	if err != nil {
+		osFile.Close()
+
+		return 0, err
+	}
+
+	err = osFile.Sync()
+	if err != nil {
+		osFile.Close()
+
+		return 0, err
+	}
+
+	return size, osFile.Close()
+}
+
+func isUploadTemp(name string) bool {
+	return name == tusStateDir || strings.HasPrefix(name, strings.TrimSuffix(uploadTempPattern, "*"))
+}
+
+func removeUpload(root *os.Root, tempName string) {
+	err := root.Remove(tempName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Error("failed to remove partial upload", "path", filepath.Join(root.Name(), tempName), "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanUploadName(t *testing.T) {
+	tests := []struct {
+		name  string
+		clean string
+		ok    bool
+	}{
+		{name: "a.txt", clean: "a.txt", ok: true},
+		{name: "docs/a.txt", clean: "docs/a.txt", ok: true},
+		{name: "/docs/a.txt", clean: "docs/a.txt", ok: true},
+		{name: "docs//./a.txt", clean: "docs/a.txt", ok: true},
+		{name: `docs\a.txt`, clean: "docs/a.txt", ok: true},
+		{name: "../a.txt", clean: "a.txt", ok: true},
+		{name: `..\..\a.txt`, clean: "a.txt", ok: true},
+		{name: "docs/../../a.txt", clean: "a.txt", ok: true},
+		{name: "a b (1).txt", clean: "a b (1).txt", ok: true},
+		{name: "", ok: false},
+		{name: "/", ok: false},
+		{name: "..", ok: false},
+		{name: `\`, ok: false},
+		{name: tusStateDir + "/ABC.json", ok: false},
+		{name: "docs/" + tusStateDir, ok: false},
+		{name: `docs\.goserve-upload-1a2b`, ok: false},
+		{name: "../" + tusStateDir + "/ABC", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clean, err := cleanUploadName(tt.name)
+			if (err == nil) != tt.ok {
+				t.Fatalf("cleanUploadName(%q) error = %v, want ok %v", tt.name, err, tt.ok)
+			}
+
+			if tt.ok && clean != tt.clean {
+				t.Errorf("cleanUploadName(%q) = %q, want %q", tt.name, clean, tt.clean)
+			}
+		})
+	}
+}
+
+func TestIsUploadTemp(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{name: ".goserve-upload-1a2b", ok: true},
+		{name: tusStateDir, ok: true},
+		{name: ".goserve-uploads", ok: false},
+		{name: "goserve-upload-1a2b", ok: false},
+		{name: "a.txt", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := isUploadTemp(tt.name)
+			if ok != tt.ok {
+				t.Errorf("isUploadTemp(%q) = %v, want %v", tt.name, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWriteUploadMode(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "reference.txt"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reference, err := os.Stat(filepath.Join(dir, "reference.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer root.Close()
+
+	c := NewController(nil, ControllerConfig{})
+
+	for _, conflict := range []ConflictPolicy{ConflictReject, ConflictOverwrite, ConflictRename, ConflictVersion} {
+		t.Run(string(conflict), func(t *testing.T) {
+			name, _, err := c.writeUpload(root, string(conflict)+".txt", strings.NewReader("content"), conflict)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := root.Stat(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Mode().Perm() != reference.Mode().Perm() {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), reference.Mode().Perm())
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package files
 
 import (
+	"io/fs"
 	"net/url"
 	"strconv"
 )
@@ -22,3 +23,7 @@ func parseQueryBool(u *url.URL, key string) bool {
 
 	return b
 }
+
+func fileETag(info fs.FileInfo) string {
+	return `"` + strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36) + `"`
+}
@@ -0,0 +1,35 @@
+package altsvc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const DefaultMaxAge = 24 * time.Hour
+
+type Options struct {
+	Ports  []int
+	MaxAge time.Duration
+}
+
+func AdvertiseHTTP3(next http.Handler, o Options) http.Handler {
+	maxAge := o.MaxAge
+
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && r.ProtoMajor < 3 {
+			addr, ok := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr)
+			if ok && slices.Contains(o.Ports, addr.Port) {
+				w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"; ma=%d`, addr.Port, int(maxAge.Seconds())))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+type contextKey struct{}
+
+type Options struct {
+	Realm                   string
+	Credentials             *Credentials
+	ClientIdentities        []string
+	Uploads                 *Credentials
+	UploadsClientIdentities []string
+	IsUpload                func(r *http.Request) bool
+	Skip                    func(r *http.Request) bool
+}
+
+func Authenticate(next http.Handler, o Options) http.Handler {
+	if o.Realm == "" {
+		o.Realm = "goserve"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials := o.Credentials
+		clientIdentities := o.ClientIdentities
+
+		isUpload := !isReadMethod(r.Method) || (o.IsUpload != nil && o.IsUpload(r))
+
+		if isUpload && (!o.Uploads.Empty() || len(o.UploadsClientIdentities) > 0) {
+			credentials = o.Uploads
+			clientIdentities = o.UploadsClientIdentities
+		}
+
+		identity, ok := MatchClientIdentity(r, clientIdentities)
+		if ok {
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), identity)))
+
+			return
+		}
+
+		identity, hasIdentity := ClientIdentity(r)
+
+		if (credentials.Empty() && len(clientIdentities) == 0) || (o.Skip != nil && o.Skip(r)) {
+			if hasIdentity {
+				r = r.WithContext(WithUser(r.Context(), identity))
+			}
+
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if credentials.Empty() {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || !credentials.Verify(username, password) {
+			w.Header().Set("WWW-Authenticate", "Basic realm="+strconv.Quote(o.Realm)+`, charset="UTF-8"`)
+
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), username)))
+	})
+}
+
+func WithUser(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, contextKey{}, username)
+}
+
+func User(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(contextKey{}).(string)
+
+	return username, ok
+}
+
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+	"slices"
+)
+
+const AnyClientIdentity = "*"
+
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	return r.TLS.VerifiedChains[0][0], true
+}
+
+func ClientIdentity(r *http.Request) (string, bool) {
+	certificate, ok := ClientCertificate(r)
+	if !ok {
+		return "", false
+	}
+
+	names := CertificateNames(certificate)
+
+	if len(names) == 0 {
+		return "", false
+	}
+
+	return names[0], true
+}
+
+func MatchClientIdentity(r *http.Request, allowed []string) (string, bool) {
+	certificate, ok := ClientCertificate(r)
+	if !ok {
+		return "", false
+	}
+
+	names := CertificateNames(certificate)
+
+	for _, name := range names {
+		if slices.Contains(allowed, name) {
+			return name, true
+		}
+	}
+
+	if len(names) > 0 && slices.Contains(allowed, AnyClientIdentity) {
+		return names[0], true
+	}
+
+	return "", false
+}
+
+func CertificateNames(certificate *x509.Certificate) []string {
+	var names []string
+
+	if certificate.Subject.CommonName != "" {
+		names = append(names, certificate.Subject.CommonName)
+	}
+
+	return append(names, CertificateSANs(certificate)...)
+}
+
+func CertificateSANs(certificate *x509.Certificate) []string {
+	var sans []string
+
+	sans = append(sans, certificate.EmailAddresses...)
+	sans = append(sans, certificate.DNSNames...)
+
+	for _, uri := range certificate.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	return sans
+}
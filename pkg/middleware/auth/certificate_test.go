@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestMatchClientIdentity(t *testing.T) {
+	certificate := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "client"},
+		DNSNames:       []string{"client.example.com"},
+		EmailAddresses: []string{"ci@example.com"},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/ci"}},
+	}
+
+	tests := []struct {
+		name     string
+		allowed  []string
+		identity string
+		ok       bool
+	}{
+		{name: "common name", allowed: []string{"client"}, identity: "client", ok: true},
+		{name: "dns san", allowed: []string{"client.example.com"}, identity: "client.example.com", ok: true},
+		{name: "email san", allowed: []string{"ci@example.com"}, identity: "ci@example.com", ok: true},
+		{name: "uri san", allowed: []string{"spiffe://example.com/ci"}, identity: "spiffe://example.com/ci", ok: true},
+		{name: "any", allowed: []string{AnyClientIdentity}, identity: "client", ok: true},
+		{name: "not allowed", allowed: []string{"other"}, ok: false},
+		{name: "empty", allowed: nil, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}}
+
+			identity, ok := MatchClientIdentity(r, tt.allowed)
+			if identity != tt.identity || ok != tt.ok {
+				t.Errorf("MatchClientIdentity() = %q, %v, want %q, %v", identity, ok, tt.identity, tt.ok)
+			}
+		})
+	}
+
+	t.Run("no certificate", func(t *testing.T) {
+		_, ok := MatchClientIdentity(httptest.NewRequest("GET", "/", nil), []string{AnyClientIdentity})
+		if ok {
+			t.Error("MatchClientIdentity() matched a request without a certificate")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Credentials struct {
+	passwords map[string]password
+}
+
+type password struct {
+	value  string
+	hashed bool
+}
+
+func NewCredentials() *Credentials {
+	return &Credentials{
+		passwords: make(map[string]password),
+	}
+}
+
+func (c *Credentials) AddUser(userPass string) error {
+	username, pass, ok := strings.Cut(userPass, ":")
+	if !ok || username == "" {
+		return errors.New("invalid credentials: expected user:pass")
+	}
+
+	c.passwords[username] = password{
+		value: pass,
+	}
+
+	return nil
+}
+
+func (c *Credentials) LoadHtpasswd(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			slog.Error("failed to close htpasswd file", "path", name, "error", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		username, hash, ok := strings.Cut(text, ":")
+		if !ok || username == "" {
+			return fmt.Errorf("%s:%d: invalid htpasswd entry", name, line)
+		}
+
+		if !isSupportedHash(hash) {
+			return fmt.Errorf("%s:%d: unsupported password hash for user %q", name, line, username)
+		}
+
+		c.passwords[username] = password{
+			value:  hash,
+			hashed: true,
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (c *Credentials) Empty() bool {
+	return c == nil || len(c.passwords) == 0
+}
+
+func (c *Credentials) Verify(username, pass string) bool {
+	expected, ok := c.passwords[username]
+	if !ok {
+		return false
+	}
+
+	if !expected.hashed {
+		return subtle.ConstantTimeCompare([]byte(expected.value), []byte(pass)) == 1
+	}
+
+	switch {
+	case isBcryptHash(expected.value):
+		return bcrypt.CompareHashAndPassword([]byte(expected.value), []byte(pass)) == nil
+
+	case strings.HasPrefix(expected.value, "$5$"), strings.HasPrefix(expected.value, "$6$"):
+		return verifySHACrypt(expected.value, pass)
+
+	case strings.HasPrefix(expected.value, "{SHA}"):
+		sum := sha1.Sum([]byte(pass))
+
+		return subtle.ConstantTimeCompare([]byte(expected.value[len("{SHA}"):]), []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1
+
+	default:
+		return false
+	}
+}
+
+func isSupportedHash(hash string) bool {
+	return isBcryptHash(hash) ||
+		strings.HasPrefix(hash, "$5$") ||
+		strings.HasPrefix(hash, "$6$") ||
+		strings.HasPrefix(hash, "{SHA}")
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCredentialsVerify(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	htpasswd := strings.Join([]string{
+		"# comment",
+		"",
+		"bcrypt:" + string(bcryptHash),
+		"sha256:$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+		"sha256rounds:$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA",
+		"sha512:$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+		"sha512rounds:$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+		"sha1:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	}, "\n")
+
+	name := filepath.Join(t.TempDir(), ".htpasswd")
+
+	err = os.WriteFile(name, []byte(htpasswd), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials := NewCredentials()
+
+	err = credentials.LoadHtpasswd(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = credentials.AddUser("plain:secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		ok       bool
+	}{
+		{name: "plain", username: "plain", password: "secret", ok: true},
+		{name: "plain wrong password", username: "plain", password: "Secret", ok: false},
+		{name: "bcrypt", username: "bcrypt", password: "password", ok: true},
+		{name: "bcrypt wrong password", username: "bcrypt", password: "passwore", ok: false},
+		{name: "sha256", username: "sha256", password: "Hello world!", ok: true},
+		{name: "sha256 wrong password", username: "sha256", password: "Hello world", ok: false},
+		{name: "sha256 rounds", username: "sha256rounds", password: "Hello world!", ok: true},
+		{name: "sha512", username: "sha512", password: "Hello world!", ok: true},
+		{name: "sha512 wrong password", username: "sha512", password: "hello world!", ok: false},
+		{name: "sha512 rounds", username: "sha512rounds", password: "Hello world!", ok: true},
+		{name: "sha1", username: "sha1", password: "password", ok: true},
+		{name: "sha1 wrong password", username: "sha1", password: "", ok: false},
+		{name: "unknown user", username: "nobody", password: "password", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := credentials.Verify(tt.username, tt.password)
+			if ok != tt.ok {
+				t.Errorf("Verify(%q, %q) = %v, want %v", tt.username, tt.password, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCredentialsLoadHtpasswdErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		htpasswd string
+	}{
+		{name: "missing separator", htpasswd: "user"},
+		{name: "missing user", htpasswd: ":{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="},
+		{name: "md5 hash", htpasswd: "user:$apr1$salt$hash"},
+		{name: "plain password", htpasswd: "user:password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), ".htpasswd")
+
+			err := os.WriteFile(name, []byte(tt.htpasswd), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = NewCredentials().LoadHtpasswd(name)
+			if err == nil {
+				t.Errorf("LoadHtpasswd(%q) succeeded, want error", tt.htpasswd)
+			}
+		})
+	}
+}
+
+func TestCredentialsAddUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		userPass string
+		ok       bool
+	}{
+		{name: "valid", userPass: "user:pass", ok: true},
+		{name: "password with colon", userPass: "user:pa:ss", ok: true},
+		{name: "empty password", userPass: "user:", ok: true},
+		{name: "missing separator", userPass: "user", ok: false},
+		{name: "missing user", userPass: ":pass", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewCredentials().AddUser(tt.userPass)
+			if (err == nil) != tt.ok {
+				t.Errorf("AddUser(%q) error = %v, want ok %v", tt.userPass, err, tt.ok)
+			}
+		})
+	}
+}
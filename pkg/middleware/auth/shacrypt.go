@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+const (
+	shaCryptDefaultRounds = 5000
+	shaCryptMinRounds     = 1000
+	shaCryptMaxRounds     = 999999999
+	shaCryptMaxSaltLength = 16
+	shaCryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	sha256CryptPermutation = [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+	}
+	sha512CryptPermutation = [][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+		{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41},
+	}
+)
+
+func verifySHACrypt(hashed, password string) bool {
+	var (
+		newHash     func() hash.Hash
+		permutation [][3]int
+	)
+
+	switch {
+	case strings.HasPrefix(hashed, "$5$"):
+		newHash = sha256.New
+		permutation = sha256CryptPermutation
+
+	case strings.HasPrefix(hashed, "$6$"):
+		newHash = sha512.New
+		permutation = sha512CryptPermutation
+
+	default:
+		return false
+	}
+
+	parts := strings.Split(hashed[3:], "$")
+
+	rounds := shaCryptDefaultRounds
+	roundsCustom := false
+
+	if len(parts) == 3 {
+		value, ok := strings.CutPrefix(parts[0], "rounds=")
+		if !ok {
+			return false
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return false
+		}
+
+		rounds = min(max(n, shaCryptMinRounds), shaCryptMaxRounds)
+		roundsCustom = true
+		parts = parts[1:]
+	}
+
+	if len(parts) != 2 {
+		return false
+	}
+
+	salt := parts[0]
+
+	if len(salt) > shaCryptMaxSaltLength {
+		salt = salt[:shaCryptMaxSaltLength]
+	}
+
+	computed := shaCrypt(newHash, permutation, []byte(password), []byte(salt), rounds)
+
+	var prefix string
+
+	if roundsCustom {
+		prefix = "rounds=" + strconv.Itoa(rounds) + "$"
+	}
+
+	expected := hashed[:3] + prefix + salt + "$" + computed
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(hashed)) == 1
+}
+
+func shaCrypt(newHash func() hash.Hash, permutation [][3]int, password, salt []byte, rounds int) string {
+	h := newHash()
+
+	h.Write(password)
+	h.Write(salt)
+	h.Write(password)
+
+	b := h.Sum(nil)
+
+	h.Reset()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(repeatBytes(b, len(password)))
+
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write(b)
+		} else {
+			h.Write(password)
+		}
+	}
+
+	a := h.Sum(nil)
+
+	h.Reset()
+
+	for range len(password) {
+		h.Write(password)
+	}
+
+	p := repeatBytes(h.Sum(nil), len(password))
+
+	h.Reset()
+
+	for range 16 + int(a[0]) {
+		h.Write(salt)
+	}
+
+	s := repeatBytes(h.Sum(nil), len(salt))
+
+	c := a
+
+	for i := range rounds {
+		h.Reset()
+
+		if i%2 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+
+		if i%3 != 0 {
+			h.Write(s)
+		}
+
+		if i%7 != 0 {
+			h.Write(p)
+		}
+
+		if i%2 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+
+		c = h.Sum(nil)
+	}
+
+	var out strings.Builder
+
+	for _, group := range permutation {
+		encodeSHACrypt(&out, c[group[0]], c[group[1]], c[group[2]], 4)
+	}
+
+	if len(c) == sha256.Size {
+		encodeSHACrypt(&out, 0, c[31], c[30], 3)
+	} else {
+		encodeSHACrypt(&out, 0, 0, c[63], 2)
+	}
+
+	return out.String()
+}
+
+func encodeSHACrypt(out *strings.Builder, b2, b1, b0 byte, n int) {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+
+	for range n {
+		out.WriteByte(shaCryptAlphabet[w&0x3f])
+
+		w >>= 6
+	}
+}
+
+func repeatBytes(b []byte, n int) []byte {
+	out := make([]byte, 0, n)
+
+	for len(out) < n {
+		out = append(out, b[:min(len(b), n-len(out))]...)
+	}
+
+	return out
+}
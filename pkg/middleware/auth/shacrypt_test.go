@@ -0,0 +1,94 @@
+package auth
+
+import "testing"
+
+func TestVerifySHACrypt(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashed   string
+		password string
+		ok       bool
+	}{
+		{
+			name:     "sha256",
+			hashed:   "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+			password: "Hello world!",
+			ok:       true,
+		},
+		{
+			name:     "sha256 rounds",
+			hashed:   "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA",
+			password: "Hello world!",
+			ok:       true,
+		},
+		{
+			name:     "sha256 minimum rounds",
+			hashed:   "$5$rounds=1000$roundstoolow$yfvwcWrQ8l/K0DAWyuPMDNHpIVlTQebY9l/gL972bIC",
+			password: "the minimum number is still observed",
+			ok:       true,
+		},
+		{
+			name:     "sha256 rounds below minimum",
+			hashed:   "$5$rounds=10$roundstoolow$yfvwcWrQ8l/K0DAWyuPMDNHpIVlTQebY9l/gL972bIC",
+			password: "the minimum number is still observed",
+			ok:       false,
+		},
+		{
+			name:     "sha256 truncated salt",
+			hashed:   "$5$toolongsaltstrin$AqIYMzL1pTg3CdcReUVWtv.S.SUIxbVqiKsYpVZs3q9",
+			password: "password",
+			ok:       true,
+		},
+		{
+			name:     "sha512",
+			hashed:   "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			password: "Hello world!",
+			ok:       true,
+		},
+		{
+			name:     "sha512 rounds",
+			hashed:   "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+			password: "Hello world!",
+			ok:       true,
+		},
+		{
+			name:     "wrong password",
+			hashed:   "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			password: "Hello world",
+			ok:       false,
+		},
+		{
+			name:     "tampered hash",
+			hashed:   "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc6",
+			password: "Hello world!",
+			ok:       false,
+		},
+		{
+			name:     "invalid rounds",
+			hashed:   "$5$rounds=many$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+			password: "Hello world!",
+			ok:       false,
+		},
+		{
+			name:     "missing hash",
+			hashed:   "$5$saltstring",
+			password: "Hello world!",
+			ok:       false,
+		},
+		{
+			name:     "unsupported prefix",
+			hashed:   "$1$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5",
+			password: "Hello world!",
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := verifySHACrypt(tt.hashed, tt.password)
+			if ok != tt.ok {
+				t.Errorf("verifySHACrypt(%q, %q) = %v, want %v", tt.hashed, tt.password, ok, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	middlewarehttp "github.com/cmgsj/goserve/pkg/middleware/http"
+)
+
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+var encodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+var incompressibleContentTypes = []string{
+	"application/gzip",
+	"application/vnd.rar",
+	"application/x-7z-compressed",
+	"application/x-brotli",
+	"application/x-bzip2",
+	"application/x-gzip",
+	"application/x-rar-compressed",
+	"application/x-xz",
+	"application/zip",
+	"application/zstd",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"video/",
+}
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingZstd: {
+		New: func() any {
+			encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+			return encoder
+		},
+	},
+	EncodingBrotli: {
+		New: func() any {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		},
+	},
+	EncodingGzip: {
+		New: func() any {
+			return gzip.NewWriter(nil)
+		},
+	},
+}
+
+type Options struct {
+	MinSize int
+}
+
+func CompressResponses(next http.Handler, o Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middlewarehttp.AddVary(w.Header(), "Accept-Encoding")
+
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		encoding := middlewarehttp.NegotiateEncoding(r.Header.Get("Accept-Encoding"), encodings)
+
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        o.MinSize,
+			statusCode:     http.StatusOK,
+		}
+
+		defer writer.close()
+
+		next.ServeHTTP(writer, r)
+	})
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+
+	encoding    string
+	minSize     int
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	encoder     encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+
+		return
+	}
+
+	w.wroteHeader = true
+	w.statusCode = code
+}
+
+func (w *compressWriter) Write(content []byte) (int, error) {
+	w.wroteHeader = true
+
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(content)
+		}
+
+		return w.ResponseWriter.Write(content)
+	}
+
+	if !w.compressible() {
+		w.decide(false)
+
+		return w.ResponseWriter.Write(content)
+	}
+
+	w.buf = append(w.buf, content...)
+
+	if len(w.buf) >= w.minSize {
+		err := w.flushBuffer(true)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(content), nil
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		err := w.flushBuffer(w.compressible() && len(w.buf) >= w.minSize)
+		if err != nil {
+			slog.Error("failed to write response", "error", err)
+
+			return
+		}
+	}
+
+	flusher, ok := w.encoder.(interface{ Flush() error })
+	if ok {
+		err := flusher.Flush()
+		if err != nil {
+			slog.Error("failed to flush response encoder", "encoding", w.encoding, "error", err)
+
+			return
+		}
+	}
+
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) compressible() bool {
+	if w.statusCode != http.StatusOK {
+		return false
+	}
+
+	header := w.Header()
+
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentLength := header.Get("Content-Length")
+
+	if contentLength != "" {
+		size, err := strconv.Atoi(contentLength)
+		if err == nil && size < w.minSize {
+			return false
+		}
+	}
+
+	contentType := header.Get("Content-Type")
+
+	if contentType == "" {
+		return true
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		return strings.HasPrefix(contentType, "image/svg+xml")
+	}
+
+	for _, incompressible := range incompressibleContentTypes {
+		if strings.HasPrefix(contentType, incompressible) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+
+	header := w.Header()
+
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && !w.compressible() {
+		compress = false
+	}
+
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+
+		etag := header.Get("ETag")
+
+		if etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		w.encoder = encoderPools[w.encoding].Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+}
+
+func (w *compressWriter) flushBuffer(compress bool) error {
+	w.decide(compress)
+
+	buf := w.buf
+
+	w.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+
+		return err
+	}
+
+	_, err := w.ResponseWriter.Write(buf)
+
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader {
+			return
+		}
+
+		err := w.flushBuffer(false)
+		if err != nil {
+			slog.Error("failed to write response", "error", err)
+
+			return
+		}
+	}
+
+	if w.encoder == nil {
+		return
+	}
+
+	err := w.encoder.Close()
+	if err != nil {
+		slog.Error("failed to close response encoder", "encoding", w.encoding, "error", err)
+
+		return
+	}
+
+	w.encoder.Reset(nil)
+
+	encoderPools[w.encoding].Put(w.encoder)
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func NegotiateEncoding(acceptEncoding string, encodings []string) string {
+	qualities := make(map[string]float64)
+
+	wildcard := -1.0
+
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+
+		param, value, ok := strings.Cut(strings.TrimSpace(params), "=")
+		if ok && strings.TrimSpace(param) == "q" {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+
+			quality = q
+		}
+
+		if coding == "*" {
+			wildcard = quality
+		} else {
+			qualities[coding] = quality
+		}
+	}
+
+	var (
+		best        string
+		bestQuality float64
+	)
+
+	for _, encoding := range encodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = wildcard
+		}
+
+		if quality > bestQuality {
+			best = encoding
+			bestQuality = quality
+		}
+	}
+
+	return best
+}
+
+func AddVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for v := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), field) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", field)
+}
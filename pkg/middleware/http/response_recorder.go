@@ -34,6 +34,10 @@ func (r *responseRecorder) Write(content []byte) (int, error) {
 	return n, err
 }
 
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (r *responseRecorder) StatusCode() int {
 	return r.statusCode
 }
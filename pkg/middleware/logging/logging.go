@@ -3,9 +3,11 @@ package logging
 import (
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/cmgsj/goserve/pkg/files"
+	"github.com/cmgsj/goserve/pkg/middleware/auth"
 	middlewarehttp "github.com/cmgsj/goserve/pkg/middleware/http"
 )
 
@@ -16,13 +18,22 @@ func LogRequests(next http.Handler) http.Handler {
 		start := time.Now()
 
 		defer func() {
-			slog.Info(
-				r.Method+" "+r.URL.Path,
+			args := []any{
 				"address", r.RemoteAddr,
 				"status", http.StatusText(recorder.StatusCode()),
 				"size", files.FormatSizeMetric(float64(recorder.BytesWritten()), files.ShortestLengthPrecision),
 				"duration", time.Since(start),
-			)
+			}
+
+			certificate, ok := auth.ClientCertificate(r)
+			if ok {
+				args = append(args,
+					"client_subject", certificate.Subject.String(),
+					"client_san", strings.Join(auth.CertificateSANs(certificate), ","),
+				)
+			}
+
+			slog.Info(r.Method+" "+r.URL.Path, args...)
 		}()
 
 		next.ServeHTTP(recorder, r)
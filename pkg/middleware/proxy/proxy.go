@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strings"
+)
+
+type forwardedContextKey struct{}
+
+type forwarded struct {
+	proto  string
+	prefix string
+}
+
+type Options struct {
+	TrustedProxies []netip.Prefix
+	TrustUnix      bool
+}
+
+func ParseTrustedProxies(values []string) (Options, error) {
+	var o Options
+
+	for _, value := range values {
+		if value == "unix" {
+			o.TrustUnix = true
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				return Options{}, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		o.TrustedProxies = append(o.TrustedProxies, prefix.Masked())
+	}
+
+	return o, nil
+}
+
+func ForwardedHeaders(next http.Handler, o Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !o.trustedPeer(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		clientIP, ok := o.clientIP(r.Header.Values("X-Forwarded-For"))
+		if ok {
+			r.RemoteAddr = clientIP.String()
+		}
+
+		var fwd forwarded
+
+		proto := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")))
+
+		if proto == "http" || proto == "https" {
+			fwd.proto = proto
+		}
+
+		prefix := strings.TrimSpace(r.Header.Get("X-Forwarded-Prefix"))
+
+		if prefix != "" {
+			fwd.prefix = strings.TrimSuffix(path.Clean("/"+prefix), "/")
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), forwardedContextKey{}, fwd))
+
+		if fwd.prefix != "" {
+			w = &prefixWriter{
+				ResponseWriter: w,
+				prefix:         fwd.prefix,
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func Scheme(r *http.Request) string {
+	fwd, _ := r.Context().Value(forwardedContextKey{}).(forwarded)
+
+	if fwd.proto != "" {
+		return fwd.proto
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+func ClientIP(r *http.Request) netip.Addr {
+	return remoteAddr(r.RemoteAddr)
+}
+
+func Prefix(ctx context.Context) string {
+	fwd, _ := ctx.Value(forwardedContextKey{}).(forwarded)
+
+	return fwd.prefix
+}
+
+func (o Options) trustedPeer(r *http.Request) bool {
+	if isUnixPeer(r) {
+		return o.TrustUnix
+	}
+
+	return o.trusted(remoteAddr(r.RemoteAddr))
+}
+
+func (o Options) trusted(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	for _, prefix := range o.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o Options) clientIP(values []string) (netip.Addr, bool) {
+	var hops []string
+
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	var client netip.Addr
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		client = addr.Unmap()
+
+		if !o.trusted(client) {
+			break
+		}
+	}
+
+	return client, client.IsValid()
+}
+
+func isUnixPeer(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+
+	return ok && addr.Network() == "unix"
+}
+
+func remoteAddr(address string) netip.Addr {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
+
+type prefixWriter struct {
+	http.ResponseWriter
+
+	prefix string
+}
+
+func (w *prefixWriter) WriteHeader(code int) {
+	location := w.Header().Get("Location")
+
+	if strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") {
+		w.Header().Set("Location", w.prefix+location)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *prefixWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,47 @@
+package redirect
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cmgsj/goserve/pkg/middleware/proxy"
+)
+
+const defaultHTTPSPort = 443
+
+type Options struct {
+	Port int
+}
+
+func RedirectHTTPS(next http.Handler, o Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if proxy.Scheme(r) == "https" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+
+		if o.Port != 0 && o.Port != defaultHTTPSPort {
+			host = net.JoinHostPort(host, strconv.Itoa(o.Port))
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			host = "[" + host + "]"
+		}
+
+		target := url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     proxy.Prefix(r.Context()) + r.URL.Path,
+			RawQuery: r.URL.RawQuery,
+		}
+
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,49 @@
+package redirect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		target   string
+		port     int
+		location string
+	}{
+		{name: "hostname", host: "example.com", target: "/a?b=c", location: "https://example.com/a?b=c"},
+		{name: "hostname with port", host: "example.com:8080", target: "/", port: 8443, location: "https://example.com:8443/"},
+		{name: "default port", host: "example.com:8080", target: "/", port: 443, location: "https://example.com/"},
+		{name: "ipv4", host: "127.0.0.1:8080", target: "/", port: 8443, location: "https://127.0.0.1:8443/"},
+		{name: "ipv6 with port", host: "[::1]:8080", target: "/", port: 8443, location: "https://[::1]:8443/"},
+		{name: "ipv6 without port", host: "[::1]", target: "/", port: 8443, location: "https://[::1]:8443/"},
+		{name: "ipv6 default port", host: "[::1]", target: "/", location: "https://[::1]/"},
+	}
+
+	handler := func(port int) http.Handler {
+		return RedirectHTTPS(http.NotFoundHandler(), Options{Port: port})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			r.Host = tt.host
+
+			w := httptest.NewRecorder()
+
+			handler(tt.port).ServeHTTP(w, r)
+
+			if w.Code != http.StatusPermanentRedirect {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusPermanentRedirect)
+			}
+
+			location := w.Header().Get("Location")
+			if location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
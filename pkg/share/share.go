@@ -0,0 +1,117 @@
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PathParam      = "share_path"
+	ExpiresParam   = "share_expires"
+	SignatureParam = "share_signature"
+	UserParam      = "share_user"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid share link signature")
+	ErrExpired          = errors.New("share link expired")
+	ErrPathNotShared    = errors.New("path not covered by share link")
+)
+
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{
+		key: key,
+	}
+}
+
+func (s *Signer) Sign(urlPath, username string, expires time.Time) url.Values {
+	urlPath = cleanPath(urlPath)
+
+	expiresUnix := strconv.FormatInt(expires.Unix(), 10)
+
+	values := url.Values{
+		PathParam:      {urlPath},
+		ExpiresParam:   {expiresUnix},
+		SignatureParam: {s.signature(urlPath, expiresUnix, username)},
+	}
+
+	if username != "" {
+		values.Set(UserParam, username)
+	}
+
+	return values
+}
+
+func (s *Signer) Verify(r *http.Request, now time.Time) (string, error) {
+	query := r.URL.Query()
+
+	sharedPath := query.Get(PathParam)
+	expiresUnix := query.Get(ExpiresParam)
+	username := query.Get(UserParam)
+	signature := query.Get(SignatureParam)
+
+	if strings.Contains(username, "\n") || !hmac.Equal([]byte(signature), []byte(s.signature(sharedPath, expiresUnix, username))) {
+		return "", ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(expiresUnix, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	if now.After(time.Unix(expires, 0)) {
+		return "", ErrExpired
+	}
+
+	requestPath := cleanPath(r.URL.Path)
+
+	if requestPath != sharedPath && !strings.HasPrefix(requestPath, strings.TrimSuffix(sharedPath, "/")+"/") {
+		return "", ErrPathNotShared
+	}
+
+	return username, nil
+}
+
+func (s *Signer) signature(urlPath, expiresUnix, username string) string {
+	mac := hmac.New(sha256.New, s.key)
+
+	mac.Write([]byte(urlPath + "\n" + expiresUnix + "\n" + username))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func HasSignature(r *http.Request) bool {
+	return r.URL.Query().Has(SignatureParam)
+}
+
+func Query(r *http.Request) url.Values {
+	query := r.URL.Query()
+
+	values := url.Values{
+		PathParam:      {query.Get(PathParam)},
+		ExpiresParam:   {query.Get(ExpiresParam)},
+		SignatureParam: {query.Get(SignatureParam)},
+	}
+
+	if query.Has(UserParam) {
+		values.Set(UserParam, query.Get(UserParam))
+	}
+
+	return values
+}
+
+func cleanPath(urlPath string) string {
+	return path.Clean("/" + urlPath)
+}
@@ -0,0 +1,150 @@
+package share
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner([]byte("key"))
+
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		sharedPath  string
+		username    string
+		expires     time.Time
+		requestPath string
+		now         time.Time
+		tamper      func(values url.Values)
+		want        string
+		err         error
+	}{
+		{name: "file", sharedPath: "/docs/a.txt", expires: expires, requestPath: "/docs/a.txt", now: now},
+		{name: "unclean shared path", sharedPath: "docs/../docs/a.txt", expires: expires, requestPath: "/docs/a.txt", now: now},
+		{name: "dir", sharedPath: "/docs/", expires: expires, requestPath: "/docs/", now: now},
+		{name: "file in dir", sharedPath: "/docs/", expires: expires, requestPath: "/docs/sub/a.txt", now: now},
+		{name: "root", sharedPath: "/", expires: expires, requestPath: "/any/file.txt", now: now},
+		{name: "user", sharedPath: "/docs/", username: "alice", expires: expires, requestPath: "/docs/a.txt", now: now, want: "alice"},
+		{name: "at expiry", sharedPath: "/docs/", expires: expires, requestPath: "/docs/", now: expires},
+		{name: "expired", sharedPath: "/docs/", expires: expires, requestPath: "/docs/", now: expires.Add(time.Second), err: ErrExpired},
+		{name: "sibling path", sharedPath: "/docs", expires: expires, requestPath: "/docs-private/a.txt", now: now, err: ErrPathNotShared},
+		{name: "parent path", sharedPath: "/docs/sub/", expires: expires, requestPath: "/docs/", now: now, err: ErrPathNotShared},
+		{name: "dot dot request path", sharedPath: "/docs/", expires: expires, requestPath: "/docs/../secret.txt", now: now, err: ErrPathNotShared},
+		{
+			name:        "tampered path",
+			sharedPath:  "/docs/",
+			expires:     expires,
+			requestPath: "/",
+			now:         now,
+			tamper:      func(values url.Values) { values.Set(PathParam, "/") },
+			err:         ErrInvalidSignature,
+		},
+		{
+			name:        "tampered expiry",
+			sharedPath:  "/docs/",
+			expires:     expires,
+			requestPath: "/docs/",
+			now:         expires.Add(time.Second),
+			tamper:      func(values url.Values) { values.Set(ExpiresParam, "9999999999") },
+			err:         ErrInvalidSignature,
+		},
+		{
+			name:        "tampered user",
+			sharedPath:  "/docs/",
+			username:    "alice",
+			expires:     expires,
+			requestPath: "/docs/",
+			now:         now,
+			tamper:      func(values url.Values) { values.Set(UserParam, "admin") },
+			err:         ErrInvalidSignature,
+		},
+		{
+			name:        "added user",
+			sharedPath:  "/docs/",
+			expires:     expires,
+			requestPath: "/docs/",
+			now:         now,
+			tamper:      func(values url.Values) { values.Set(UserParam, "admin") },
+			err:         ErrInvalidSignature,
+		},
+		{
+			name:        "removed user",
+			sharedPath:  "/docs/",
+			username:    "alice",
+			expires:     expires,
+			requestPath: "/docs/",
+			now:         now,
+			tamper:      func(values url.Values) { values.Del(UserParam) },
+			err:         ErrInvalidSignature,
+		},
+		{
+			name:        "missing signature",
+			sharedPath:  "/docs/",
+			expires:     expires,
+			requestPath: "/docs/",
+			now:         now,
+			tamper:      func(values url.Values) { values.Del(SignatureParam) },
+			err:         ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := signer.Sign(tt.sharedPath, tt.username, tt.expires)
+
+			if tt.tamper != nil {
+				tt.tamper(values)
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.URL.Path = tt.requestPath
+			r.URL.RawQuery = values.Encode()
+
+			username, err := signer.Verify(r, tt.now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.err)
+			}
+
+			if username != tt.want {
+				t.Errorf("Verify() = %q, want %q", username, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignerVerifyOtherKey(t *testing.T) {
+	values := NewSigner([]byte("key")).Sign("/docs/", "", time.Unix(1700003600, 0))
+
+	r := httptest.NewRequest("GET", "/docs/?"+values.Encode(), nil)
+
+	_, err := NewSigner([]byte("other")).Verify(r, time.Unix(1700000000, 0))
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	values := NewSigner([]byte("key")).Sign("/docs/", "alice", time.Unix(1700003600, 0))
+
+	values.Set("sort", "name")
+
+	r := httptest.NewRequest("GET", "/docs/?"+values.Encode(), nil)
+
+	query := Query(r)
+
+	if query.Has("sort") {
+		t.Error("Query() kept a non-share parameter")
+	}
+
+	for _, param := range []string{PathParam, ExpiresParam, SignatureParam, UserParam} {
+		if query.Get(param) != values.Get(param) {
+			t.Errorf("Query().Get(%q) = %q, want %q", param, query.Get(param), values.Get(param))
+		}
+	}
+}